@@ -0,0 +1,61 @@
+package roachpulse
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+)
+
+// Bench records phase timings when set, as it is with -benchmark. It is nil
+// otherwise, in which case phases are run without being recorded.
+var Bench *PhaseTimer
+
+type phase struct {
+	name   string
+	d      time.Duration
+	allocs uint64
+	bytes  uint64
+}
+
+// PhaseTimer records the wall time and allocations of named phases.
+type PhaseTimer struct {
+	phases []phase
+}
+
+// Time runs fn, recording its duration and allocations under name.
+func (t *PhaseTimer) Time(name string, fn func()) {
+	if t == nil {
+		fn()
+		return
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	fn()
+	d := time.Since(start)
+	runtime.ReadMemStats(&after)
+	t.phases = append(t.phases, phase{
+		name:   name,
+		d:      d,
+		allocs: after.Mallocs - before.Mallocs,
+		bytes:  after.TotalAlloc - before.TotalAlloc,
+	})
+}
+
+// Print writes a table of the recorded phases to stderr.
+func (t *PhaseTimer) Print() {
+	if t == nil {
+		return
+	}
+	var total phase
+	w := os.Stderr
+	fmt.Fprintf(w, "\n%-20s %10s %12s %12s\n", "phase", "time", "allocs", "bytes")
+	for _, p := range t.phases {
+		fmt.Fprintf(w, "%-20s %9.3fs %12d %12d\n", p.name, p.d.Seconds(), p.allocs, p.bytes)
+		total.d += p.d
+		total.allocs += p.allocs
+		total.bytes += p.bytes
+	}
+	fmt.Fprintf(w, "%-20s %9.3fs %12d %12d\n", "total", total.d.Seconds(), total.allocs, total.bytes)
+}
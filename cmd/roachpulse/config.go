@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// configFile is the name of the file, looked for in the current directory
+// and then in the -c cache directory, which sets defaults for flags.
+const configFile = ".roachpulse.json"
+
+// loadConfig sets every flag which was not given on the command line from
+// the config files, if they exist. The file in the current directory takes
+// precedence over the one in the cache directory, and may itself set -c.
+func loadConfig() error {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	if err := applyConfig(configFile, set); err != nil {
+		return err
+	}
+	return applyConfig(filepath.Join(*cache, configFile), set)
+}
+
+// applyConfig reads a JSON object mapping flag names to values from path
+// and sets each flag not already in set, adding it to set. A list of values
+// sets a repeatable flag such as -label once per element. A missing file is
+// ignored.
+func applyConfig(path string, set map[string]bool) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var config map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&config); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if set[name] {
+			continue
+		}
+		if flag.Lookup(name) == nil {
+			return fmt.Errorf("%s: unknown flag -%s", path, name)
+		}
+		values := []interface{}{config[name]}
+		if list, ok := config[name].([]interface{}); ok {
+			values = list
+		}
+		for _, v := range values {
+			if err := flag.Set(name, fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("%s: invalid value %v for -%s: %s", path, v, name, err)
+			}
+		}
+		set[name] = true
+	}
+	return nil
+}
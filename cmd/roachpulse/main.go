@@ -0,0 +1,931 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation"
+	"github.com/google/go-github/github"
+	"github.com/petermattis/roachpulse"
+	"golang.org/x/oauth2"
+)
+
+var (
+	cache = flag.String("c", filepath.Join(os.Getenv("HOME"), ".roachpulse"),
+		"cached project data, stored in an owner/repo subdirectory")
+	update    = flag.Bool("u", false, "refresh cached project data")
+	project   = flag.String("p", "cockroachdb/cockroach", "comma-separated GitHub owner/repo names, whose issues are analyzed together")
+	tokenFile = flag.String("token", "",
+		"read GitHub token personal access token from `file` (default $HOME/.github-issue-token)")
+
+	appID = flag.Int64("app-id", 0,
+		"authenticate as the GitHub App with this `id` instead of with a personal access token (requires -installation-id and -private-key)")
+	installationID = flag.Int64("installation-id", 0, "GitHub App installation `id` to authenticate as with -app-id")
+	privateKey     = flag.String("private-key", "", "read the GitHub App private key for -app-id from `file`, in PEM format")
+
+	approvals    = flag.Bool("approvals", false, "report the distribution of approvals on merged pull requests")
+	reopenRate   = flag.Bool("reopen-rate", false, "report the rate of issues reopened shortly after being closed")
+	reopenWindow = flag.Int("reopen-window", 7, "`days` after a close within which a reopen counts for -reopen-rate")
+	triageCSV    = flag.String("triage-coverage", "", "write the weekly share of new issues labeled within -triage-sla to `file` as CSV")
+	triageSLA    = flag.Int("triage-sla", 2, "business `days` within which a new issue should be labeled")
+	needsInfo    = flag.Bool("needs-info", false, "report how long issues spend carrying the -needs-info-label label")
+	needsInfoLbl = flag.String("needs-info-label", "needs-info", "`label` marking issues waiting on the reporter")
+	fetchFiles   = flag.Bool("fetch-files", false, "fetch the files changed by pull requests when refreshing")
+	testPRs      = flag.Bool("test-prs", false, "report the share of merged pull requests that change tests (requires -fetch-files data)")
+	testPatterns = flag.String("test-patterns", "*_test.go,test,tests,testdata",
+		"comma-separated `patterns` matching test file names or directories")
+	logBuckets      = flag.Bool("log-buckets", false, "also summarize ages using log-scaled buckets for better tail resolution")
+	summaryOnly     = flag.Bool("summary-only", false, "print a single line summarizing the headline metrics")
+	exportHist      = flag.String("export-hist", "", "write the bucketed histogram of -hist-metric to `file` in -hist-format")
+	histMetric      = flag.String("hist-metric", "pr-age", "histogram metric to export with -export-hist")
+	histFormat      = flag.String("hist-format", "csv", "`format` of -export-hist: csv or json")
+	histResolution  = flag.Int("hist-resolution", 0, "merge -export-hist buckets into exponential buckets, this `number` per doubling of value (0 exports the raw buckets)")
+	breadth         = flag.Bool("breadth", false, "report how many distinct issues each user acted on within -breadth-days")
+	breadthDays     = flag.Int("breadth-days", 90, "window in `days` for -breadth")
+	exitZeroOnEmpty = flag.Bool("exit-zero-on-empty", false, "exit successfully when no issues match")
+	labelAges       = flag.Bool("label-ages", false, "report the age of open issues per label")
+	rebases         = flag.Bool("rebases", false, "report how often pull requests were force pushed after a base branch change to resolve conflicts")
+	comparePeriods  = flag.String("compare-periods", "",
+		"compare metrics for issues created in two `periods`, e.g. 2017-Q1,2017-Q2 or 2017-01-01..2017-02-15,2017-02-16..2017-03-31")
+	pings             = flag.Bool("pings", false, "report open issues whose latest comments are only pings by the author or bots")
+	pingCount         = flag.Int("ping-count", 2, "minimum `number` of trailing author or bot comments for -pings")
+	silentCloses      = flag.Bool("silent-closes", false, "report closed issues that never received a non-bot comment")
+	benchmark         = flag.Bool("benchmark", false, "report the time and allocations of each phase")
+	labelConflicts    = flag.Bool("label-conflicts", false, "list issues carrying mutually exclusive labels")
+	conflictingLabels = flag.String("conflicting-labels", "C-bug,C-enhancement",
+		"semicolon-separated `sets` of comma-separated labels that should not appear together")
+	missingLabel            = flag.Bool("missing-label", false, "list open issues without a label starting with -label-prefix")
+	labelPrefix             = flag.String("label-prefix", "C-", "label `prefix` every issue is expected to carry, for -missing-label")
+	milestoneSizes          = flag.Bool("milestone-sizes", false, "report the number of issues per milestone")
+	burndown                = flag.Bool("burndown", false, "report the weekly number of open issues in each milestone")
+	includeClosedMilestones = flag.Bool("include-closed-milestones", false, "include closed milestones in -milestone-sizes and -burndown")
+	quantilesFlag           = flag.String("quantiles", "50,75,90,95,99", "comma-separated `quantiles` printed by histogram reports")
+	firstResponse           = flag.Bool("first-response", false, "report the time to first response on issues")
+	teamFile                = flag.String("team", "", "read maintainer GitHub logins, one per line, from `file`")
+	communityPRs            = flag.Bool("community-prs", false, "report the share of community pull requests that are merged")
+	bucket                  = flag.String("bucket", "", "report issues opened and closed per `period`: week, month or quarter")
+	jsonOutput              = flag.Bool("json", false, "print the computed metrics as JSON instead of the human-readable report")
+	csvFile                 = flag.String("csv", "", "write one row per issue to `file` as CSV")
+	workers                 = flag.Int("workers", 8, "`number` of issues whose details are fetched concurrently when refreshing")
+	reconcile               = flag.Bool("reconcile", false, "remove cached issues that were deleted or transferred on GitHub")
+	store                   = flag.String("store", "dir", "cache storage backend: dir (one file per issue) or ndjson (a single gzip-compressed file)")
+	serve                   = flag.String("serve", "", "serve metrics in Prometheus text format on `addr` (e.g. :8080) at /metrics")
+	reactions               = flag.Bool("reactions", false, "report the open issues with the most positive reactions")
+	top                     = flag.Int("top", 20, "`number` of entries listed by -reactions, -leaderboard, -label-pairs, -label-churn, -reopens, -abandoned, -duplicates and -report")
+	reviewLatency           = flag.Bool("review-latency", false, "report the time from a pull request being opened to its first review")
+	backlog                 = flag.Bool("backlog", false, "report the number of open issues at the end of each -bucket period (default month)")
+	since                   = flag.String("since", "", "only include issues created on or after `date` (YYYY-MM-DD)")
+	until                   = flag.String("until", "", "only include issues created on or before `date` (YYYY-MM-DD)")
+	stale                   = flag.Int("stale", 0, "report open issues and pull requests without timeline activity in the last `days`")
+	quiet                   = flag.Bool("q", false, "do not print progress while loading and refreshing")
+	contributors            = flag.Bool("contributors", false, "list the authors of issues and pull requests with their counts")
+	leaderboard             = flag.Bool("leaderboard", false, "rank contributors by merged pull requests, pull requests and issues authored")
+	excludeBots             = flag.Bool("exclude-bots", false, "exclude issues and pull requests authored by bots from all metrics")
+	botsFlag                = flag.String("bots", "", "comma-separated `logins` to treat as bots in addition to those typed as bots by GitHub")
+	engagement              = flag.Bool("engagement", false, "report the number of comments and participants per issue")
+	dryRun                  = flag.Bool("dry-run", false, "list the issues updated since the last refresh and estimate the requests -u would make, without changing the cache")
+	labelPairs              = flag.Bool("label-pairs", false, "report the label pairs most often applied to the same issue")
+	triage                  = flag.Bool("triage", false, "report the time from an issue being filed to its first label")
+	issueNum                = flag.Int("issue", 0, "with -u, refresh only issue `number` rather than every updated issue; with -graph, center the graph on it")
+	reopens                 = flag.Bool("reopens", false, "report the share of closed issues ever reopened and the issues reopened most often")
+	reportFormat            = flag.String("report", "", "print a summary report in `format` (md for Markdown) instead of the usual reports")
+	mergeSparkline          = flag.Bool("merge-sparkline", false, "chart the number of pull requests merged each week as a sparkline")
+	workload                = flag.Bool("workload", false, "report the number and mean age of open issues per assignee")
+	abandoned               = flag.Bool("abandoned", false, "report pull requests closed without being merged, by who closed them")
+	timelineNum             = flag.Int("timeline", 0, "print the cached timeline of issue `number` as a readable log")
+	labelTime               = flag.String("label-time", "", "report how long issues spend carrying each of the comma-separated `labels`, e.g. X-blocked,waiting-for-review")
+	export                  = flag.String("export", "", "write one flattened JSON object per issue to stdout in `format` (ndjson) for loading into a data warehouse")
+	cpuProfile              = flag.String("cpuprofile", "", "write a CPU profile of the run to `file`")
+	memProfile              = flag.String("memprofile", "", "write a heap profile taken at the end of the run to `file`")
+	fetchSizes              = flag.Bool("fetch-sizes", false, "fetch the number of lines changed by pull requests when refreshing (one request per pull request)")
+	prSizes                 = flag.Bool("pr-sizes", false, "report merge time by pull request size (requires -fetch-sizes or -fetch-files data)")
+	timeseriesCSV           = flag.String("timeseries-csv", "", "write weekly counts of opened, closed and merged items to `file` as CSV")
+	duplicates              = flag.Bool("duplicates", false, "report the share of issues closed as duplicates and the issues most often duplicated")
+	graph                   = flag.String("graph", "", "write the cross references between issues to `file` as a Graphviz DOT graph")
+	graphDepth              = flag.Int("graph-depth", 2, "with -graph and -issue, include issues up to `n` references away from the issue")
+	labelAlias              = flag.String("label-alias", "", "read old=new label renames, one per line, from `file` and report renamed labels under their current names")
+	funnel                  = flag.Bool("funnel", false, "report how many community issue filers went on to have a pull request merged, and how long it took")
+	labelChurn              = flag.Bool("label-churn", false, "report the labels most often removed relative to how often they are applied")
+	openAge                 = flag.Bool("open-age", false, "report the age of issues which are still open")
+	maintainerResponse      = flag.Bool("maintainer-response", false, "report each -team member's time to reply to community comments")
+	compact                 = flag.Bool("compact", false, "rewrite the cached issues, dropping fields no longer used, and report the space reclaimed")
+	staleAfter              = flag.Duration("stale-after", 24*time.Hour, "warn when the cache was last refreshed longer than this `duration` ago, unless -q is set (0 disables the warning)")
+	labelMode               = flag.String("label-mode", "all", "`mode` of the -label filter, which every report including -label-ages sees: all, requiring every label, or any, requiring one of them")
+)
+
+// stringList is a flag.Value accumulating the values of a repeated flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+var labels stringList
+
+func init() {
+	flag.Var(&labels, "label", "only include issues carrying `label` (may be repeated; see -label-mode)")
+}
+
+// settingFlags are the flags which configure where the cache is and how the
+// run behaves without requesting any output or filtering the issues.
+var settingFlags = map[string]bool{
+	"c": true, "p": true, "q": true, "store": true, "team": true, "bots": true,
+	"token": true, "app-id": true, "installation-id": true, "private-key": true,
+	"workers": true, "fetch-files": true, "fetch-sizes": true, "stale-after": true,
+	"benchmark": true, "cpuprofile": true, "memprofile": true,
+}
+
+// contributorsOnly returns true if -contributors is the only output
+// requested of fs, so the contributors saved alongside the cache can be
+// listed without loading every issue.
+func contributorsOnly(fs *flag.FlagSet) bool {
+	var enabled, other bool
+	fs.Visit(func(f *flag.Flag) {
+		switch {
+		case f.Name == "contributors":
+			enabled = f.Value.String() == "true"
+		case !settingFlags[f.Name]:
+			other = true
+		}
+	})
+	return enabled && !other
+}
+
+func prettyJSON(v interface{}) string {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		fatal(err)
+	}
+	return string(data)
+}
+
+// tokenEnv lists the environment variables consulted for a GitHub token, in
+// order of precedence.
+var tokenEnv = []string{"ROACHPULSE_TOKEN", "GITHUB_TOKEN"}
+
+// envToken returns the GitHub token from the environment, or an empty string
+// if none is set.
+func envToken() string {
+	for _, name := range tokenEnv {
+		if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// readToken returns the GitHub personal access token stored in -token or
+// $HOME/.github-issue-token, which must not be accessible by other users.
+func readToken() string {
+	const short = ".github-issue-token"
+	filename := filepath.Clean(os.Getenv("HOME") + "/" + short)
+	shortFilename := filepath.Clean("$HOME/" + short)
+	if *tokenFile != "" {
+		filename = *tokenFile
+		shortFilename = *tokenFile
+	}
+	token, err := readTokenFile(filename, shortFilename)
+	if err != nil {
+		if !os.IsNotExist(err) && !os.IsPermission(err) {
+			fatalf("reading token: %s", err)
+		}
+		fatal("reading token: ", err, "\n\n"+
+			"Please create a personal access token at https://github.com/settings/tokens/new\n"+
+			"and write it to ", shortFilename, " or set $ROACHPULSE_TOKEN or $GITHUB_TOKEN\n"+
+			"to use this program.\n"+
+			"The token only needs the repo scope, or private_repo if you want to\n"+
+			"view or edit issues for private repositories.\n"+
+			"The benefit of using a personal access token over using your GitHub\n"+
+			"password directly is that you can limit its use and revoke it at any time.\n\n")
+	}
+	return token
+}
+
+// readTokenFile returns the token stored in filename, or an error if it
+// cannot be read or is accessible by users other than its owner. The file
+// is referred to as shortFilename in errors.
+func readTokenFile(filename, shortFilename string) (string, error) {
+	// Stat the opened file rather than the path so that the mode checked is
+	// that of the file actually read.
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if fi.Mode()&0077 != 0 {
+		return "", fmt.Errorf("%s mode is %#o, want %#o", shortFilename, fi.Mode()&0777, fi.Mode()&0700)
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	// GitHub personal access token, from https://github.com/settings/applications.
+	return strings.TrimSpace(string(data)), nil
+}
+
+// makeClient returns a GitHub client which authenticates using the
+// transport returned by newTransport.
+func makeClient() *github.Client {
+	t, err := newTransport()
+	if err != nil {
+		fatal(err)
+	}
+	client, err := roachpulse.NewClient(&http.Client{Transport: t}, "")
+	if err != nil {
+		fatal(err)
+	}
+	return client
+}
+
+// newTransport returns a transport which authenticates as the GitHub App
+// installation given by -app-id and -installation-id when -app-id is set,
+// and with a personal access token otherwise.
+func newTransport() (http.RoundTripper, error) {
+	if *appID != 0 {
+		return ghinstallation.NewKeyFromFile(http.DefaultTransport, *appID, *installationID, *privateKey)
+	}
+	authToken := envToken()
+	if authToken == "" {
+		authToken = readToken()
+	}
+	return &oauth2.Transport{
+		Source: &tokenSource{AccessToken: authToken},
+	}, nil
+}
+
+type tokenSource oauth2.Token
+
+func (t *tokenSource) Token() (*oauth2.Token, error) {
+	return (*oauth2.Token)(t), nil
+}
+
+// makeProject returns the project named by owner/repo, cached in its own
+// subdirectory of -c and configured from the command line flags.
+func makeProject(project string) *roachpulse.Project {
+	f := strings.Split(project, "/")
+	if len(f) != 2 {
+		fatal("invalid form for -p argument: must be owner/repo, like cockroachdb/cockroach")
+	}
+	owner, repo := f[0], f[1]
+	dir := roachpulse.ProjectCacheDir(*cache, owner, repo)
+	if err := roachpulse.MigrateLegacyCache(*cache, dir, owner, repo); err != nil {
+		fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fatal(err)
+	}
+	p := roachpulse.NewProject(owner, repo, dir)
+	p.Store = *store
+	p.Workers = *workers
+	p.FetchFiles = *fetchFiles
+	p.FetchSizes = *fetchSizes
+	p.DryRun = *dryRun
+	return p
+}
+
+// stopProfiles finishes the profiles started by startProfiles. It must be
+// called before exiting with os.Exit, which skips deferred calls, so that an
+// interrupted run still leaves usable profiles. Fatal errors go through
+// fatal and fatalf, which call it.
+var stopProfiles = func() {}
+
+// fatal is equivalent to log.Fatal, but first stops the profiles so that
+// they are written despite the exit. It is used in place of log.Fatal
+// wherever the profiles may have been started.
+func fatal(v ...interface{}) {
+	stopProfiles()
+	log.Fatal(v...)
+}
+
+// fatalf is equivalent to log.Fatalf, but first stops the profiles.
+func fatalf(format string, v ...interface{}) {
+	stopProfiles()
+	log.Fatalf(format, v...)
+}
+
+// startProfiles starts the CPU profile requested by -cpuprofile and arranges
+// for stopProfiles to write it and the heap profile requested by
+// -memprofile.
+func startProfiles() {
+	var cpu *os.File
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			log.Fatal(err)
+		}
+		cpu = f
+	}
+	stopProfiles = func() {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			if err := cpu.Close(); err != nil {
+				log.Print(err)
+			}
+		}
+		if *memProfile != "" {
+			// Collect garbage first so that the profile reflects the live
+			// heap.
+			runtime.GC()
+			if err := roachpulse.WriteFile(*memProfile, pprof.WriteHeapProfile); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+}
+
+// interruptible returns a context which is cancelled on SIGINT or SIGTERM.
+// Only the first signal is caught, so a second one kills the process.
+func interruptible() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-c
+		signal.Stop(c)
+		fmt.Fprintf(roachpulse.Progress, "\n%s: stopping\n", sig)
+		cancel()
+	}()
+	return ctx
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: roachpulse [flags] [query]
+
+A query is a list of terms such as is:open, is:pr, author:LOGIN,
+label:NAME, milestone:TITLE or closed:>YYYY-MM-DD. Issues matching
+every term are listed instead of reporting metrics.
+
+Defaults for any flag not given on the command line are read from a
+JSON object mapping flag names to values, e.g. {"p": "owner/repo",
+"team": "team.txt"}, in .roachpulse.json in the current directory or
+in the -c directory.
+
+`)
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("roachpulse: ")
+
+	if err := loadConfig(); err != nil {
+		log.Fatal(err)
+	}
+
+	if q, err := roachpulse.ParseQuantiles(*quantilesFlag); err != nil {
+		log.Fatalf("invalid -quantiles: %s", err)
+	} else {
+		roachpulse.Quantiles = q
+	}
+
+	if *bucket != "" && roachpulse.BucketKey(time.Time{}, *bucket) == "" {
+		log.Fatalf("invalid -bucket %q: must be week, month or quarter", *bucket)
+	}
+
+	var query func(*roachpulse.Issue) bool
+	if flag.NArg() > 0 {
+		var err error
+		if query, err = roachpulse.ParseQuery(flag.Args()); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var sinceTime, untilTime time.Time
+	if *since != "" {
+		t, err := time.Parse("2006-01-02", *since)
+		if err != nil {
+			log.Fatalf("invalid -since %q: expected YYYY-MM-DD", *since)
+		}
+		sinceTime = t
+	}
+	if *until != "" {
+		t, err := time.Parse("2006-01-02", *until)
+		if err != nil {
+			log.Fatalf("invalid -until %q: expected YYYY-MM-DD", *until)
+		}
+		// -until is inclusive of the named day.
+		untilTime = t.AddDate(0, 0, 1)
+	}
+	if !sinceTime.IsZero() && !untilTime.IsZero() && !sinceTime.Before(untilTime) {
+		log.Fatalf("-since %s is after -until %s", *since, *until)
+	}
+
+	if *appID != 0 && (*installationID == 0 || *privateKey == "") {
+		log.Fatal("-app-id requires -installation-id and -private-key")
+	}
+	if *appID == 0 && (*installationID != 0 || *privateKey != "") {
+		log.Fatal("-installation-id and -private-key require -app-id")
+	}
+
+	if *compact && (*update || *reconcile || *dryRun) {
+		log.Fatal("-compact cannot be combined with -u, -reconcile or -dry-run")
+	}
+
+	roachpulse.ParseBots(*botsFlag)
+	if *labelAlias != "" {
+		if err := roachpulse.LoadLabelAliases(*labelAlias); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *reportFormat != "" && *reportFormat != "md" {
+		log.Fatalf("invalid -report %q: must be md", *reportFormat)
+	}
+
+	if *labelMode != "all" && *labelMode != "any" {
+		log.Fatalf("invalid -label-mode %q: must be all or any", *labelMode)
+	}
+
+	if *histFormat != "csv" && *histFormat != "json" {
+		log.Fatalf("invalid -hist-format %q: must be csv or json", *histFormat)
+	}
+	if *histResolution < 0 {
+		log.Fatalf("invalid -hist-resolution %d: must not be negative", *histResolution)
+	}
+
+	if *export != "" && *export != "ndjson" {
+		log.Fatalf("invalid -export %q: must be ndjson", *export)
+	}
+
+	if *store != roachpulse.StoreDir && *store != roachpulse.StoreNDJSON {
+		log.Fatalf("invalid -store %q: must be %s or %s", *store, roachpulse.StoreDir, roachpulse.StoreNDJSON)
+	}
+
+	if *quiet {
+		roachpulse.Progress = ioutil.Discard
+	}
+
+	startProfiles()
+	defer stopProfiles()
+
+	if *benchmark {
+		roachpulse.Bench = &roachpulse.PhaseTimer{}
+		defer roachpulse.Bench.Print()
+	}
+
+	names := strings.Split(*project, ",")
+	if *issueNum > 0 && (*update || *dryRun) && len(names) > 1 {
+		fatalf("-issue requires a single -p project")
+	}
+	projects := make([]*roachpulse.Project, len(names))
+	for j, name := range names {
+		projects[j] = makeProject(name)
+		if *teamFile != "" {
+			if err := projects[j].LoadTeam(*teamFile); err != nil {
+				fatal(err)
+			}
+		}
+	}
+	if contributorsOnly(flag.CommandLine) && query == nil && len(projects) == 1 {
+		// The contributors are saved alongside the cache, so there is no
+		// need to load every issue unless they are filtered or other output
+		// is requested. Caches written before they were saved fall back to
+		// a full load.
+		p := projects[0]
+		cs, ok := p.SavedContributors()
+		if !ok {
+			if err := p.Load(); err != nil {
+				fatal(err)
+			}
+			cs = p.Contributors()
+		}
+		roachpulse.ReportContributors(cs)
+		return
+	}
+
+	for _, p := range projects {
+		if err := p.Load(); err != nil {
+			fatal(err)
+		}
+	}
+	if *compact {
+		for _, p := range projects {
+			before, after, err := p.Compact()
+			if err != nil {
+				fatal(err)
+			}
+			fmt.Printf("%s: compacted %d bytes to %d (%d reclaimed)\n",
+				p.Name(), before, after, before-after)
+		}
+		return
+	}
+	if *dryRun {
+		*update = true
+	}
+	var limits *github.RateLimits
+	if *update || *reconcile {
+		ctx := interruptible()
+		client := makeClient()
+		if *update {
+			roachpulse.FetchRateLimits(ctx, client)
+		}
+		for _, p := range projects {
+			if *update {
+				roachpulse.Bench.Time("refresh", func() {
+					if *issueNum > 0 {
+						if err := p.RefreshIssue(ctx, client, *issueNum); err != nil {
+							fatal(err)
+						}
+						return
+					}
+					if err := p.Refresh(ctx, client); err != nil {
+						fatalf("refresh interrupted (%s); run with -u again to resume", err)
+					}
+				})
+			}
+			if *reconcile {
+				roachpulse.Bench.Time("reconcile", func() {
+					if err := p.Reconcile(ctx, client); err != nil {
+						if ctx.Err() != nil {
+							fatalf("reconcile interrupted (%s)", err)
+						}
+						fatal(err)
+					}
+				})
+			}
+		}
+		if *update {
+			limits = roachpulse.FetchRateLimits(ctx, client)
+		}
+	}
+	fmt.Fprintf(roachpulse.Progress, "\n")
+
+	if !*update && !*quiet && *staleAfter > 0 {
+		for _, p := range projects {
+			if p.RefreshedAt.IsZero() {
+				log.Printf("warning: %s has never been refreshed; run with -u", p.Name())
+			} else if age := time.Since(p.RefreshedAt); age > *staleAfter {
+				ago := age.Round(time.Minute).String()
+				if age >= 48*time.Hour {
+					ago = fmt.Sprintf("%d days", int(age.Hours()/24))
+				}
+				log.Printf("warning: %s was last refreshed %s ago, at %s; run with -u to refresh",
+					p.Name(), ago, p.RefreshedAt.Format(time.RFC3339))
+			}
+		}
+	}
+
+	p := projects[0]
+	if len(projects) > 1 {
+		p = roachpulse.Merge(projects)
+	}
+	p.AliasLabels()
+
+	if *timelineNum > 0 {
+		i := p.Issue(*timelineNum)
+		if i == nil {
+			fatalf("issue %d is not cached; refresh it with -u -issue %d", *timelineNum, *timelineNum)
+		}
+		if err := i.WriteTimeline(os.Stdout); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	if !sinceTime.IsZero() || !untilTime.IsZero() {
+		p = p.WithIssues(p.FilterByCreated(sinceTime, untilTime))
+	}
+
+	if *excludeBots {
+		p = p.Filter(func(i *roachpulse.Issue) bool {
+			return !roachpulse.IsBot(i.User)
+		})
+	}
+
+	if len(labels) > 0 {
+		matchAny := *labelMode == "any"
+		p = p.Filter(func(i *roachpulse.Issue) bool {
+			return i.HasLabels(labels, matchAny)
+		})
+	}
+
+	if query != nil {
+		p = p.Filter(query)
+	}
+
+	if *serve != "" {
+		fatal(roachpulse.ServeMetrics(*serve, p))
+	}
+
+	issues := p.Issues()
+	if len(issues) == 0 {
+		fmt.Printf("0 issues matched\n")
+		if !*exitZeroOnEmpty {
+			stopProfiles()
+			os.Exit(1)
+		}
+		return
+	}
+
+	if query != nil {
+		for _, i := range issues {
+			fmt.Printf("%s %s\n", i.Ref(), i.GetTitle())
+		}
+		return
+	}
+
+	if *comparePeriods != "" {
+		roachpulse.Bench.Time("compare-periods", func() {
+			if err := roachpulse.ComparePeriodsReport(p, *comparePeriods); err != nil {
+				fatal(err)
+			}
+		})
+		return
+	}
+
+	if *jsonOutput {
+		roachpulse.Bench.Time("json", func() {
+			m := roachpulse.ComputeMetrics(p)
+			m.RateLimits = roachpulse.NewRateLimits(limits)
+			fmt.Println(prettyJSON(m))
+		})
+		return
+	}
+
+	if *export != "" {
+		roachpulse.Bench.Time("export", func() {
+			if err := p.ExportNDJSON(os.Stdout); err != nil {
+				fatal(err)
+			}
+		})
+		return
+	}
+
+	if *reportFormat != "" {
+		roachpulse.Bench.Time("report", func() {
+			if err := roachpulse.WriteMarkdownReport(os.Stdout, p, *stale, *top); err != nil {
+				fatal(err)
+			}
+		})
+		return
+	}
+
+	if *summaryOnly {
+		roachpulse.Bench.Time("summary", func() {
+			fmt.Println(roachpulse.SummaryLine(p, time.Now()))
+		})
+		return
+	}
+
+	// TODO:
+	// - Mean time to close/merge pull requests.
+	// - Graph on a per weekly basis.
+	now := time.Now()
+	reports := []struct {
+		name    string
+		enabled bool
+		run     func()
+	}{
+		{"age", true, func() {
+			roachpulse.PrintDaysSummary("age", roachpulse.PRAgeHistogram(p))
+			if *logBuckets {
+				roachpulse.PrintLogSummary("age", roachpulse.LogHistogram(roachpulse.CloseTimes(p, roachpulse.IsPR)))
+			}
+		}},
+		{"issue-close", true, func() {
+			roachpulse.PrintDaysSummary("issue close", roachpulse.IssueCloseHistogram(p))
+			if *logBuckets {
+				roachpulse.PrintLogSummary("issue close", roachpulse.LogHistogram(roachpulse.CloseTimes(p, roachpulse.IsIssue)))
+			}
+		}},
+		{"open-age", *openAge, func() {
+			h := roachpulse.OpenAgeHistogram(p, now)
+			roachpulse.PrintDaysSummary("open issue age", h)
+			if h.TotalCount() > 0 {
+				fmt.Printf("  max=%dd\n", h.Max())
+			}
+		}},
+		{"first-response", *firstResponse, func() {
+			roachpulse.PrintSummary("first response", roachpulse.FirstResponseHistogram(p), "h")
+		}},
+		{"maintainer-response", *maintainerResponse, func() {
+			roachpulse.ReportMaintainerResponse(p)
+		}},
+		{"review-latency", *reviewLatency, func() {
+			roachpulse.PrintSummary("review latency", roachpulse.ReviewLatencyHistogram(p), "h")
+		}},
+		{"community-prs", *communityPRs, func() {
+			roachpulse.ReportCommunityPRs(p)
+		}},
+		{"funnel", *funnel, func() {
+			roachpulse.ReportContributorFunnel(p)
+		}},
+		{"buckets", *bucket != "", func() {
+			roachpulse.ReportBuckets(p, *bucket)
+		}},
+		{"backlog", *backlog, func() {
+			mode := *bucket
+			if mode == "" {
+				mode = "month"
+			}
+			roachpulse.ReportBacklog(p, mode)
+		}},
+		{"approvals", *approvals, func() {
+			roachpulse.ReportApprovals(p)
+		}},
+		{"abandoned", *abandoned, func() {
+			roachpulse.ReportAbandonedPRs(p, *top)
+		}},
+		{"reopen-rate", *reopenRate, func() {
+			roachpulse.ReportReopenRate(p, time.Duration(*reopenWindow)*24*time.Hour)
+		}},
+		{"duplicates", *duplicates, func() {
+			roachpulse.ReportDuplicates(p, *top)
+		}},
+		{"reopens", *reopens, func() {
+			roachpulse.ReportReopens(p, *top)
+		}},
+		{"triage", *triage, func() {
+			roachpulse.PrintSummary("triage", roachpulse.TriageHistogram(p), "h")
+			fmt.Printf("  never labeled: %d\n", roachpulse.CountIssues(p, roachpulse.NeverLabeled))
+		}},
+		{"triage-coverage", *triageCSV != "", func() {
+			err := roachpulse.WriteFile(*triageCSV, func(w io.Writer) error {
+				return roachpulse.WriteTriageCoverageCSV(w, p, *triageSLA, now)
+			})
+			if err != nil {
+				fatal(err)
+			}
+		}},
+		{"needs-info", *needsInfo, func() {
+			roachpulse.ReportLabelTime(p, *needsInfoLbl, now)
+		}},
+		{"label-time", *labelTime != "", func() {
+			for _, l := range strings.Split(*labelTime, ",") {
+				roachpulse.ReportLabelTime(p, strings.TrimSpace(l), now)
+			}
+		}},
+		{"pr-sizes", *prSizes, func() {
+			roachpulse.ReportPRSizes(p)
+		}},
+		{"test-prs", *testPRs, func() {
+			roachpulse.ReportTestPRs(p, strings.Split(*testPatterns, ","))
+		}},
+		{"breadth", *breadth, func() {
+			roachpulse.ReportBreadth(p, now.Add(-time.Duration(*breadthDays)*24*time.Hour))
+		}},
+		{"label-ages", *labelAges, func() {
+			roachpulse.ReportLabelAges(p, now)
+		}},
+		{"workload", *workload, func() {
+			roachpulse.ReportWorkload(p, now)
+		}},
+		{"rebases", *rebases, func() {
+			roachpulse.ReportRebases(p)
+		}},
+		{"pings", *pings, func() {
+			roachpulse.ReportPings(p, *pingCount)
+		}},
+		{"silent-closes", *silentCloses, func() {
+			roachpulse.ReportSilentCloses(p)
+		}},
+		{"label-conflicts", *labelConflicts, func() {
+			roachpulse.ReportLabelConflicts(p, roachpulse.ParseLabelSets(*conflictingLabels))
+		}},
+		{"missing-label", *missingLabel, func() {
+			roachpulse.ReportMissingLabel(p, *labelPrefix, now)
+		}},
+		{"milestone-sizes", *milestoneSizes, func() {
+			roachpulse.ReportMilestoneSizes(p, *includeClosedMilestones)
+		}},
+		{"burndown", *burndown, func() {
+			roachpulse.ReportMilestoneBurndown(p, *includeClosedMilestones, now)
+		}},
+		{"contributors", *contributors, func() {
+			roachpulse.ReportContributors(p.Contributors())
+		}},
+		{"leaderboard", *leaderboard, func() {
+			roachpulse.ReportLeaderboard(p, *top)
+		}},
+		{"engagement", *engagement, func() {
+			comments, participants := p.EngagementHistogram()
+			roachpulse.PrintSummary("comments", comments, "")
+			roachpulse.PrintSummary("participants", participants, "")
+		}},
+		{"label-pairs", *labelPairs, func() {
+			roachpulse.ReportLabelCooccurrence(p, *top)
+		}},
+		{"label-churn", *labelChurn, func() {
+			roachpulse.ReportLabelChurn(p, *top)
+		}},
+		{"merge-sparkline", *mergeSparkline, func() {
+			roachpulse.ReportMergeSparkline(p)
+		}},
+		{"stale", *stale > 0, func() {
+			roachpulse.ReportStale(p, *stale, now)
+		}},
+		{"reactions", *reactions, func() {
+			roachpulse.ReportReactions(p, *top)
+		}},
+		{"csv", *csvFile != "", func() {
+			if err := roachpulse.WriteFile(*csvFile, p.WriteCSV); err != nil {
+				fatal(err)
+			}
+		}},
+		{"timeseries-csv", *timeseriesCSV != "", func() {
+			err := roachpulse.WriteFile(*timeseriesCSV, func(w io.Writer) error {
+				return roachpulse.WriteTimeSeriesCSV(w, p)
+			})
+			if err != nil {
+				fatal(err)
+			}
+		}},
+		{"graph", *graph != "", func() {
+			err := roachpulse.WriteFile(*graph, func(w io.Writer) error {
+				return roachpulse.WriteReferenceGraph(w, p, *issueNum, *graphDepth)
+			})
+			if err != nil {
+				fatal(err)
+			}
+		}},
+		{"export-hist", *exportHist != "", func() {
+			fn, ok := roachpulse.HistMetrics[*histMetric]
+			if !ok {
+				fatalf("unknown -hist-metric %q", *histMetric)
+			}
+			h := fn(p)
+			err := roachpulse.WriteFile(*exportHist, func(w io.Writer) error {
+				if *histFormat == "json" {
+					return roachpulse.WriteHistogramJSON(w, h, *histResolution)
+				}
+				return roachpulse.WriteHistogramCSV(w, h, *histResolution)
+			})
+			if err != nil {
+				fatal(err)
+			}
+		}},
+	}
+	for _, r := range reports {
+		if r.enabled {
+			roachpulse.Bench.Time(r.name, r.run)
+		}
+	}
+
+	// for _, m := range p.milestones {
+	// 	if m.GetState() == "open" {
+	// 		fmt.Printf("%s: %d/%d\n", m.GetTitle(), m.GetOpenIssues(), m.GetClosedIssues())
+	// 	}
+	// }
+
+	// var issues int
+	// var pullRequests int
+	// for _, i := range p.issues {
+	// 	if i.PullRequestLinks == nil {
+	// 		issues++
+	// 	} else {
+	// 		pullRequests++
+	// 	}
+	// }
+
+	// fmt.Printf("\n")
+	// fmt.Printf("%d users\n", len(p.users))
+	// fmt.Printf("%d milestones\n", len(p.milestones))
+	// fmt.Printf("%d issues\n", issues)
+	// fmt.Printf("%d pull-requests\n", pullRequests)
+
+	// metrics:
+	// - open issues/PRs
+	// - time to respond/close issue/PR
+	// - open issue/PR age
+
+	// grouping
+	// - by milestone
+	// - by user
+	// - by week/quarter
+
+	// Total open issues
+	// Mean time to close issues
+	// Mean time to respond to community reported issues
+	// Mean time to respond to community pull requests
+	// Percentage of community pull requests that are merged
+	// Ratio of issues to merged pull requests
+}
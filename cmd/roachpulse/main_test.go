@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadTokenFile(t *testing.T) {
+	dir := t.TempDir()
+
+	missing := filepath.Join(dir, "missing")
+	if _, err := readTokenFile(missing, missing); !os.IsNotExist(err) {
+		t.Errorf("missing file: expected a not exist error, got %v", err)
+	}
+
+	open := filepath.Join(dir, "open")
+	if err := ioutil.WriteFile(open, []byte("secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(open, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readTokenFile(open, "open"); err == nil || !strings.Contains(err.Error(), "open mode is 0644") {
+		t.Errorf("mode 0644: expected a mode error, got %v", err)
+	}
+
+	private := filepath.Join(dir, "private")
+	if err := ioutil.WriteFile(private, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	token, err := readTokenFile(private, "private")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "secret" {
+		t.Errorf("expected token %q, got %q", "secret", token)
+	}
+}
+
+func TestContributorsOnly(t *testing.T) {
+	testCases := []struct {
+		args []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"-contributors"}, true},
+		{[]string{"-contributors=false"}, false},
+		{[]string{"-contributors", "-p", "o/r", "-q", "-cpuprofile", "cpu.prof"}, true},
+		{[]string{"-contributors", "-leaderboard"}, false},
+		{[]string{"-json", "-contributors"}, false},
+		{[]string{"-contributors", "-compact"}, false},
+		{[]string{"-contributors", "-timeline", "1"}, false},
+		{[]string{"-contributors", "-serve", ":8080"}, false},
+		{[]string{"-contributors", "-u"}, false},
+		{[]string{"-contributors", "-label", "C-bug"}, false},
+	}
+	for _, c := range testCases {
+		t.Run(strings.Join(c.args, " "), func(t *testing.T) {
+			// Parse into a copy of the command line's flags so that the
+			// flags set by one case do not leak into the next.
+			fs := flag.NewFlagSet("roachpulse", flag.ContinueOnError)
+			flag.VisitAll(func(f *flag.Flag) {
+				fs.Var(f.Value, f.Name, f.Usage)
+			})
+			defer fs.Visit(func(f *flag.Flag) {
+				f.Value.Set(f.DefValue)
+			})
+			if err := fs.Parse(c.args); err != nil {
+				t.Fatal(err)
+			}
+			if got := contributorsOnly(fs); got != c.want {
+				t.Errorf("expected %t, got %t", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package roachpulse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parsePeriod parses a period of the form YYYY, YYYY-MM, YYYY-Qn or
+// YYYY-MM-DD..YYYY-MM-DD and returns its start (inclusive) and end
+// (exclusive). Both dates of a range are included in it.
+func parsePeriod(s string) (start, end time.Time, err error) {
+	if f := strings.SplitN(s, "..", 2); len(f) == 2 {
+		start, err1 := time.Parse("2006-01-02", f[0])
+		last, err2 := time.Parse("2006-01-02", f[1])
+		if err1 != nil || err2 != nil || last.Before(start) {
+			return start, end, fmt.Errorf("invalid period %q", s)
+		}
+		return start, last.AddDate(0, 0, 1), nil
+	}
+	if f := strings.SplitN(s, "-Q", 2); len(f) == 2 {
+		year, err1 := strconv.Atoi(f[0])
+		q, err2 := strconv.Atoi(f[1])
+		if err1 != nil || err2 != nil || q < 1 || q > 4 {
+			return start, end, fmt.Errorf("invalid period %q", s)
+		}
+		start = time.Date(year, time.Month(3*(q-1)+1), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 3, 0), nil
+	}
+	if t, err := time.Parse("2006-01", s); err == nil {
+		return t, t.AddDate(0, 1, 0), nil
+	}
+	if t, err := time.Parse("2006", s); err == nil {
+		return t, t.AddDate(1, 0, 0), nil
+	}
+	return start, end, fmt.Errorf("invalid period %q: expected YYYY, YYYY-MM, YYYY-Qn or YYYY-MM-DD..YYYY-MM-DD", s)
+}
+
+// comparison holds the value of a metric in two different windows. The
+// delta of a percentage is reported in percentage points.
+type comparison struct {
+	name    string
+	a, b    float64
+	percent bool
+}
+
+// compareMetrics computes the comparable metrics for two projects.
+func compareMetrics(a, b *Project) []comparison {
+	type metric struct {
+		name    string
+		fn      func(p *Project) float64
+		percent bool
+	}
+	metrics := []metric{
+		{"issues opened", func(p *Project) float64 {
+			return float64(CountIssues(p, IsIssue))
+		}, false},
+		{"prs opened", func(p *Project) float64 {
+			return float64(CountIssues(p, IsPR))
+		}, false},
+		{"issue close mean (d)", func(p *Project) float64 {
+			return histMean(IssueCloseHistogram(p))
+		}, false},
+		{"pr age mean (d)", func(p *Project) float64 {
+			return histMean(PRAgeHistogram(p))
+		}, false},
+		{"issue close median (d)", func(p *Project) float64 {
+			return float64(summarize(IssueCloseHistogram(p)).Median)
+		}, false},
+		{"pr age median (d)", func(p *Project) float64 {
+			return float64(summarize(PRAgeHistogram(p)).Median)
+		}, false},
+		{"merge rate (%)", func(p *Project) float64 {
+			closed, merged := mergeCounts(p)
+			if closed == 0 {
+				return 0
+			}
+			return 100 * float64(merged) / float64(closed)
+		}, true},
+		{"community merge rate (%)", func(p *Project) float64 {
+			merged, unmerged, _ := communityPRCounts(p)
+			return 100 * ratio(merged, merged+unmerged)
+		}, true},
+	}
+	rows := make([]comparison, len(metrics))
+	for j, m := range metrics {
+		rows[j] = comparison{name: m.name, a: m.fn(a), b: m.fn(b), percent: m.percent}
+	}
+	return rows
+}
+
+// printComparison prints rows as an aligned table with absolute and
+// percentage deltas. The columns are widened to fit long window names such
+// as date ranges.
+func printComparison(nameA, nameB string, rows []comparison) {
+	wa, wb := len(nameA), len(nameB)
+	if wa < 10 {
+		wa = 10
+	}
+	if wb < 10 {
+		wb = 10
+	}
+	fmt.Printf("%-24s %*s %*s %10s %8s\n", "metric", wa, nameA, wb, nameB, "delta", "delta%")
+	for _, r := range rows {
+		delta := fmt.Sprintf("%+.1f", r.b-r.a)
+		pct := "-"
+		if r.percent {
+			delta += "pp"
+		} else if r.a != 0 {
+			pct = fmt.Sprintf("%+.1f%%", 100*(r.b-r.a)/r.a)
+		}
+		fmt.Printf("%-24s %*.1f %*.1f %10s %8s\n", r.name, wa, r.a, wb, r.b, delta, pct)
+	}
+}
+
+// ComparePeriodsReport prints the metrics for issues created in each of
+// the two comma-separated periods side by side.
+func ComparePeriodsReport(p *Project, periods string) error {
+	f := strings.Split(periods, ",")
+	if len(f) != 2 {
+		return fmt.Errorf("invalid -compare-periods %q: expected two comma-separated periods", periods)
+	}
+	var scoped [2]*Project
+	for j, s := range f {
+		start, end, err := parsePeriod(s)
+		if err != nil {
+			return err
+		}
+		scoped[j] = p.WithIssues(p.FilterByCreated(start, end))
+	}
+	printComparison(f[0], f[1], compareMetrics(scoped[0], scoped[1]))
+	return nil
+}
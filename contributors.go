@@ -0,0 +1,132 @@
+package roachpulse
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// usersFile is the name of the file within the cache directory holding the
+// authors of the cached issues, written by p.save.
+const usersFile = "users.json"
+
+// Contributor is an author of issues or pull requests in the project.
+type Contributor struct {
+	ID           int    `json:"id"`
+	Login        string `json:"login"`
+	Issues       int    `json:"issues"`
+	PullRequests int    `json:"pull_requests"`
+}
+
+// Contributors returns the distinct authors of the project's issues and
+// pull requests, ordered by the number authored.
+func (p *Project) Contributors() []Contributor {
+	byID := make(map[int]*Contributor)
+	for _, i := range p.issues {
+		id := i.User.GetID()
+		if id == 0 {
+			continue
+		}
+		c := byID[id]
+		if c == nil {
+			c = &Contributor{ID: id, Login: i.User.GetLogin()}
+			byID[id] = c
+		}
+		if i.PullRequestLinks != nil {
+			c.PullRequests++
+		} else {
+			c.Issues++
+		}
+	}
+	cs := make([]Contributor, 0, len(byID))
+	for _, c := range byID {
+		cs = append(cs, *c)
+	}
+	sort.Slice(cs, func(a, b int) bool {
+		na, nb := cs[a].Issues+cs[a].PullRequests, cs[b].Issues+cs[b].PullRequests
+		if na != nb {
+			return na > nb
+		}
+		return cs[a].Login < cs[b].Login
+	})
+	return cs
+}
+
+// saveContributors writes the project's contributors to the cache.
+func (p *Project) saveContributors() {
+	saveJSON(filepath.Join(p.cacheDir, usersFile), p.Contributors())
+}
+
+// ReportContributors prints each contributor with the number of issues and
+// pull requests they authored.
+func ReportContributors(cs []Contributor) {
+	fmt.Printf("contributors (%d)\n", len(cs))
+	fmt.Printf("  %-20s %7s %7s\n", "login", "issues", "prs")
+	for _, c := range cs {
+		fmt.Printf("  %-20s %7d %7d\n", c.Login, c.Issues, c.PullRequests)
+	}
+}
+
+// ReportLeaderboard prints the top contributors ranked by the number of
+// merged pull requests they authored, then by pull requests and issues
+// opened. Bots are skipped.
+func ReportLeaderboard(p *Project, top int) {
+	type entry struct {
+		login               string
+		prs, merged, issues int
+	}
+	byID := make(map[int]*entry)
+	for _, i := range p.issues {
+		u := i.User
+		if u.GetID() == 0 || IsBot(u) {
+			continue
+		}
+		e := byID[u.GetID()]
+		if e == nil {
+			e = &entry{login: u.GetLogin()}
+			byID[u.GetID()] = e
+		}
+		if i.PullRequestLinks == nil {
+			e.issues++
+			continue
+		}
+		e.prs++
+		if i.merged() {
+			e.merged++
+		}
+	}
+	entries := make([]*entry, 0, len(byID))
+	for _, e := range byID {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		ea, eb := entries[a], entries[b]
+		if ea.merged != eb.merged {
+			return ea.merged > eb.merged
+		}
+		if ea.prs != eb.prs {
+			return ea.prs > eb.prs
+		}
+		if ea.issues != eb.issues {
+			return ea.issues > eb.issues
+		}
+		return ea.login < eb.login
+	})
+	if len(entries) > top {
+		entries = entries[:top]
+	}
+
+	fmt.Printf("leaderboard (top %d)\n", len(entries))
+	fmt.Printf("  %-20s %7s %7s %7s\n", "login", "merged", "prs", "issues")
+	for _, e := range entries {
+		fmt.Printf("  %-20s %7d %7d %7d\n", e.login, e.merged, e.prs, e.issues)
+	}
+}
+
+// SavedContributors returns the contributors saved in the project's cache
+// without loading it, and false if none were saved.
+func (p *Project) SavedContributors() ([]Contributor, bool) {
+	var cs []Contributor
+	ok := loadJSON(filepath.Join(p.cacheDir, usersFile), &cs)
+	return cs, ok
+}
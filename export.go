@@ -0,0 +1,189 @@
+package roachpulse
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// formatTime formats t for export, returning an empty string for a nil
+// time so that spreadsheets don't interpret it as the zero time.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// WriteCSV writes one row per issue, ordered by number. The age_days column
+// holds the number of days taken to close the issue and is empty for open
+// issues. The source_repo column holds the issue's owner/repo, which tells
+// the repositories of a merged project apart.
+func (p *Project) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{
+		"number", "is_pr", "author", "state", "created_at", "closed_at",
+		"age_days", "milestone", "label_count", "comment_count", "source_repo",
+	})
+	if err != nil {
+		return err
+	}
+	name := p.Name()
+	for _, num := range p.sortedIssues() {
+		i := p.issues[num]
+		source := i.Source
+		if source == "" {
+			source = name
+		}
+		var age string
+		if i.CreatedAt != nil && i.ClosedAt != nil {
+			age = strconv.FormatFloat(i.ClosedAt.Sub(*i.CreatedAt).Hours()/24, 'f', 1, 64)
+		}
+		err := cw.Write([]string{
+			strconv.Itoa(i.GetNumber()),
+			strconv.FormatBool(i.PullRequestLinks != nil),
+			i.User.GetLogin(),
+			i.GetState(),
+			formatTime(i.CreatedAt),
+			formatTime(i.ClosedAt),
+			age,
+			i.Milestone.GetTitle(),
+			strconv.Itoa(len(i.Labels)),
+			strconv.Itoa(i.GetComments()),
+			source,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportRecord is the flattened form of an issue written by ExportNDJSON.
+type exportRecord struct {
+	Number       int        `json:"number"`
+	SourceRepo   string     `json:"source_repo"`
+	IsPR         bool       `json:"is_pr"`
+	AuthorLogin  string     `json:"author_login"`
+	CreatedAt    *time.Time `json:"created_at"`
+	ClosedAt     *time.Time `json:"closed_at"`
+	Merged       bool       `json:"merged"`
+	Labels       []string   `json:"labels"`
+	Milestone    string     `json:"milestone"`
+	CommentCount int        `json:"comment_count"`
+}
+
+// ExportNDJSON writes one flattened JSON object per issue, ordered by
+// number, for loading into a data warehouse. Each issue is encoded and
+// written as it is visited rather than building the whole output.
+func (p *Project) ExportNDJSON(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	e := json.NewEncoder(bw)
+	name := p.Name()
+	for _, num := range p.sortedIssues() {
+		i := p.issues[num]
+		r := exportRecord{
+			Number:       i.GetNumber(),
+			SourceRepo:   i.Source,
+			IsPR:         i.PullRequestLinks != nil,
+			AuthorLogin:  i.User.GetLogin(),
+			CreatedAt:    utc(i.CreatedAt),
+			ClosedAt:     utc(i.ClosedAt),
+			Merged:       i.merged(),
+			Labels:       make([]string, len(i.Labels)),
+			Milestone:    i.Milestone.GetTitle(),
+			CommentCount: i.GetComments(),
+		}
+		if r.SourceRepo == "" {
+			r.SourceRepo = name
+		}
+		for j, l := range i.Labels {
+			r.Labels[j] = l.GetName()
+		}
+		if err := e.Encode(&r); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// utc returns t in UTC, or nil if t is nil.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// WriteTimeSeriesCSV writes one row per ISO week from the first to the last
+// week with any activity, including weeks in which nothing happened, so
+// that plots are evenly spaced. Each row counts the issues and pull
+// requests opened and closed that week, the pull requests merged, and the
+// number left open at the end of the week.
+func WriteTimeSeriesCSV(w io.Writer, p *Project) error {
+	type counts struct {
+		opened, closed, merged int
+	}
+	weeks := make(map[string]*counts)
+	var first, last time.Time
+	get := func(t time.Time) *counts {
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+		k := BucketKey(t, "week")
+		c := weeks[k]
+		if c == nil {
+			c = &counts{}
+			weeks[k] = c
+		}
+		return c
+	}
+	for _, i := range p.issues {
+		if i.CreatedAt != nil {
+			get(*i.CreatedAt).opened++
+		}
+		if i.ClosedAt != nil {
+			get(*i.ClosedAt).closed++
+		}
+		if t := i.mergedAt(); !t.IsZero() {
+			get(t).merged++
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"week", "opened", "closed", "merged", "net_open"}); err != nil {
+		return err
+	}
+	if first.IsZero() {
+		cw.Flush()
+		return cw.Error()
+	}
+	var open int
+	for _, k := range bucketRange(first, last, "week") {
+		c := weeks[k]
+		if c == nil {
+			c = &counts{}
+		}
+		open += c.opened - c.closed
+		err := cw.Write([]string{
+			k,
+			strconv.Itoa(c.opened),
+			strconv.Itoa(c.closed),
+			strconv.Itoa(c.merged),
+			strconv.Itoa(open),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,49 @@
+package roachpulse
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// TestWriteTimeSeriesCSV checks that net_open carries the open count over
+// from week to week rather than restarting each week.
+func TestWriteTimeSeriesCSV(t *testing.T) {
+	week := func(n int) *time.Time {
+		d := time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC).AddDate(0, 0, 7*n)
+		return &d
+	}
+	p := NewProject("o", "r", t.TempDir())
+	for n, span := range [][2]int{{0, -1}, {0, 2}, {1, -1}, {1, 3}} {
+		i := &Issue{Issue: github.Issue{Number: github.Int(n + 1), CreatedAt: week(span[0])}}
+		if span[1] >= 0 {
+			i.ClosedAt = week(span[1])
+		}
+		p.issues[n+1] = i
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTimeSeriesCSV(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, row := range rows[1:] {
+		got = append(got, row[4])
+	}
+	want := []string{"2", "4", "3", "2"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d weeks, got %d", len(want), len(got))
+	}
+	for j := range want {
+		if got[j] != want[j] {
+			t.Errorf("week %d: expected net_open %s, got %s", j, want[j], got[j])
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package roachpulse
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codahale/hdrhistogram"
+)
+
+// Quantiles holds the quantiles printed by histogram based reports. It is
+// set from -quantiles.
+var Quantiles = []float64{50, 75, 90, 95, 99}
+
+// ParseQuantiles parses a comma-separated list of quantiles, each of which
+// must lie in [0, 100].
+func ParseQuantiles(s string) ([]float64, error) {
+	var qs []float64
+	for _, f := range strings.Split(s, ",") {
+		q, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantile %q", f)
+		}
+		if q < 0 || q > 100 {
+			return nil, fmt.Errorf("quantile %v out of range [0, 100]", q)
+		}
+		qs = append(qs, q)
+	}
+	return qs, nil
+}
+
+// quantileName returns the label for quantile q, e.g. "p99.9".
+func quantileName(q float64) string {
+	return "p" + strconv.FormatFloat(q, 'f', -1, 64)
+}
+
+// formatQuantiles returns the configured quantiles of h formatted as
+// "p50=... p90=...", rendering each value with format.
+func formatQuantiles(h *hdrhistogram.Histogram, format func(v int64) string) string {
+	parts := make([]string, len(Quantiles))
+	for j, q := range Quantiles {
+		parts[j] = quantileName(q) + "=" + format(h.ValueAtQuantile(q))
+	}
+	return strings.Join(parts, " ")
+}
+
+// printPercentiles prints the configured quantiles of h, whose values are
+// in days.
+func printPercentiles(h *hdrhistogram.Histogram) {
+	printQuantiles(h, "d")
+}
+
+// printQuantiles prints the configured quantiles of h, suffixing each value
+// with unit.
+func printQuantiles(h *hdrhistogram.Histogram, unit string) {
+	if h.TotalCount() == 0 {
+		fmt.Printf("  no data\n")
+		return
+	}
+	fmt.Printf("  %s\n", formatQuantiles(h, func(v int64) string {
+		return strconv.FormatInt(v, 10) + unit
+	}))
+}
+
+// PrintDaysSummary prints the mean, median, standard deviation and
+// percentiles of h, whose values are in days.
+func PrintDaysSummary(name string, h *hdrhistogram.Histogram) {
+	PrintSummary(name, h, "d")
+}
+
+// PrintSummary prints the mean, median, standard deviation and quantiles of
+// h, suffixing values with unit.
+func PrintSummary(name string, h *hdrhistogram.Histogram, unit string) {
+	s := summarize(h)
+	if s.Count == 0 {
+		fmt.Printf("%s: no data\n", name)
+		return
+	}
+	fmt.Printf("%s: mean=%0.1f%s median=%d%s stddev=%0.1f%s\n",
+		name, s.Mean, unit, s.Median, unit, s.StdDev, unit)
+	printQuantiles(h, unit)
+}
+
+// hoursHistogram records durations in whole hours, rounding anything
+// shorter than an hour up to one hour.
+func hoursHistogram(durations []time.Duration) *hdrhistogram.Histogram {
+	h := hdrhistogram.New(1, 100*365*24, 1)
+	for _, d := range durations {
+		hours := d / time.Hour
+		if hours < 1 {
+			hours = 1
+		}
+		h.RecordValue(int64(hours))
+	}
+	return h
+}
+
+// logScale is the number of log histogram units per decade of hours.
+const logScale = 1000
+
+// daysHistogram records durations in whole days, rounding anything shorter
+// than a day up to one day.
+func daysHistogram(durations []time.Duration) *hdrhistogram.Histogram {
+	h := hdrhistogram.New(1, 100*365, 1)
+	for _, d := range durations {
+		days := d / (24 * time.Hour)
+		if days < 1 {
+			days = 1
+		}
+		h.RecordValue(int64(days))
+	}
+	return h
+}
+
+// LogHistogram records durations on a log scale so that the multi-year tail
+// is resolved as precisely as the first few days. Values are recorded as
+// log10 of the duration in hours, in units of 1/logScale, offset by one.
+func LogHistogram(durations []time.Duration) *hdrhistogram.Histogram {
+	h := hdrhistogram.New(1, 10*logScale, 3)
+	for _, d := range durations {
+		hours := d.Hours()
+		if hours < 1 {
+			hours = 1
+		}
+		h.RecordValue(int64(math.Log10(hours)*logScale) + 1)
+	}
+	return h
+}
+
+// logDays converts a value recorded by LogHistogram back into days.
+func logDays(v float64) float64 {
+	return math.Pow(10, (v-1)/logScale) / 24
+}
+
+// PrintLogSummary prints the geometric mean, median and quantiles of a
+// histogram populated by LogHistogram.
+func PrintLogSummary(name string, h *hdrhistogram.Histogram) {
+	if h.TotalCount() == 0 {
+		fmt.Printf("%s (log): no data\n", name)
+		return
+	}
+	fmt.Printf("%s (log): geomean=%0.1fd median=%0.1fd %s max=%0.1fd\n",
+		name, logDays(h.Mean()), logDays(float64(h.ValueAtQuantile(50))),
+		formatQuantiles(h, func(v int64) string {
+			return fmt.Sprintf("%0.1fd", logDays(float64(v)))
+		}),
+		logDays(float64(h.Max())))
+}
+
+// HistogramBucket is a bucket of an exported histogram.
+type HistogramBucket struct {
+	LowerBound int64 `json:"lower_bound"`
+	Count      int64 `json:"count"`
+}
+
+// histogramBuckets returns the non-empty buckets of h. If perDoubling is
+// positive the histogram's own buckets are merged into exponential buckets,
+// perDoubling of which span each doubling of value, as suits a heatmap with
+// a log-scaled axis. Buckets whose bounds round to the same integer are
+// merged too.
+func histogramBuckets(h *hdrhistogram.Histogram, perDoubling int) []HistogramBucket {
+	var buckets []HistogramBucket
+	for _, b := range h.Distribution() {
+		if b.Count == 0 {
+			continue
+		}
+		lower := b.From
+		if perDoubling > 0 && lower > 0 {
+			k := math.Floor(math.Log2(float64(lower)) * float64(perDoubling))
+			lower = int64(math.Pow(2, k/float64(perDoubling)))
+		}
+		if n := len(buckets); n > 0 && buckets[n-1].LowerBound == lower {
+			buckets[n-1].Count += b.Count
+			continue
+		}
+		buckets = append(buckets, HistogramBucket{LowerBound: lower, Count: b.Count})
+	}
+	return buckets
+}
+
+// WriteHistogramCSV writes one row per histogram bucket containing the
+// bucket's lower bound and the number of values recorded in it. See
+// histogramBuckets for perDoubling.
+func WriteHistogramCSV(w io.Writer, h *hdrhistogram.Histogram, perDoubling int) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"lower_bound", "count"}); err != nil {
+		return err
+	}
+	for _, b := range histogramBuckets(h, perDoubling) {
+		err := cw.Write([]string{
+			strconv.FormatInt(b.LowerBound, 10),
+			strconv.FormatInt(b.Count, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteHistogramJSON writes the histogram buckets as a JSON array with the
+// same fields as WriteHistogramCSV.
+func WriteHistogramJSON(w io.Writer, h *hdrhistogram.Histogram, perDoubling int) error {
+	buckets := histogramBuckets(h, perDoubling)
+	if buckets == nil {
+		buckets = []HistogramBucket{}
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(buckets)
+}
+
+// histMean returns the mean of h, or 0 if h is empty.
+func histMean(h *hdrhistogram.Histogram) float64 {
+	if h.TotalCount() == 0 {
+		return 0
+	}
+	return h.Mean()
+}
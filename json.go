@@ -0,0 +1,140 @@
+package roachpulse
+
+import (
+	"time"
+
+	"github.com/codahale/hdrhistogram"
+	"github.com/google/go-github/github"
+)
+
+// Metrics holds the computed metrics for a project in a form suitable for
+// encoding with -json.
+type Metrics struct {
+	Project          string `json:"project"`
+	Issues           int    `json:"issues"`
+	OpenIssues       int    `json:"open_issues"`
+	PullRequests     int    `json:"pull_requests"`
+	OpenPullRequests int    `json:"open_pull_requests"`
+
+	PRAgeDays          Summary `json:"pr_age_days"`
+	IssueCloseDays     Summary `json:"issue_close_days"`
+	FirstResponseHours Summary `json:"first_response_hours"`
+
+	ClosedPullRequests int     `json:"closed_pull_requests"`
+	MergedPullRequests int     `json:"merged_pull_requests"`
+	MergeRatio         float64 `json:"merge_ratio"`
+
+	CommunityMerged         int     `json:"community_merged"`
+	CommunityClosedUnmerged int     `json:"community_closed_unmerged"`
+	CommunityOpen           int     `json:"community_open"`
+	CommunityMergeRatio     float64 `json:"community_merge_ratio"`
+
+	// Repos breaks the metrics down by repository for a merged project.
+	Repos []*Metrics `json:"repos,omitempty"`
+
+	// RefreshedAt is when the project's cache was last refreshed, or the
+	// oldest of these for a merged project.
+	RefreshedAt time.Time `json:"refreshed_at"`
+
+	// RateLimits is only set when the project was refreshed.
+	RateLimits *RateLimits `json:"rate_limits,omitempty"`
+}
+
+// RateLimits holds the GitHub API quota remaining after a refresh.
+type RateLimits struct {
+	Core   *RateLimit `json:"core,omitempty"`
+	Search *RateLimit `json:"search,omitempty"`
+}
+
+// RateLimit holds the quota for one category of GitHub API requests.
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// NewRateLimits converts limits for encoding, returning nil if limits is.
+func NewRateLimits(limits *github.RateLimits) *RateLimits {
+	if limits == nil {
+		return nil
+	}
+	convert := func(r *github.Rate) *RateLimit {
+		if r == nil {
+			return nil
+		}
+		return &RateLimit{Limit: r.Limit, Remaining: r.Remaining, Reset: r.Reset.Time}
+	}
+	return &RateLimits{Core: convert(limits.Core), Search: convert(limits.Search)}
+}
+
+// Summary holds the summary statistics of a histogram. The distributions
+// reported are typically skewed by a long tail, so Median is usually more
+// representative than Mean.
+type Summary struct {
+	Count     int64            `json:"count"`
+	Mean      float64          `json:"mean"`
+	Median    int64            `json:"median"`
+	StdDev    float64          `json:"stddev"`
+	Max       int64            `json:"max"`
+	Quantiles map[string]int64 `json:"quantiles"`
+}
+
+// summarize returns the summary statistics of h, including the configured
+// quantiles. An empty histogram yields a zero summary rather than NaNs.
+func summarize(h *hdrhistogram.Histogram) Summary {
+	s := Summary{
+		Count:     h.TotalCount(),
+		Quantiles: make(map[string]int64),
+	}
+	if s.Count == 0 {
+		return s
+	}
+	s.Mean = h.Mean()
+	s.Median = h.ValueAtQuantile(50)
+	s.StdDev = h.StdDev()
+	s.Max = h.Max()
+	for _, q := range Quantiles {
+		s.Quantiles[quantileName(q)] = h.ValueAtQuantile(q)
+	}
+	return s
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// ComputeMetrics computes the metrics for p.
+func ComputeMetrics(p *Project) *Metrics {
+	m := &Metrics{
+		Project:            p.Name(),
+		RefreshedAt:        p.RefreshedAt,
+		PRAgeDays:          summarize(PRAgeHistogram(p)),
+		IssueCloseDays:     summarize(IssueCloseHistogram(p)),
+		FirstResponseHours: summarize(FirstResponseHistogram(p)),
+	}
+	for _, i := range p.issues {
+		open := i.ClosedAt == nil
+		if i.PullRequestLinks == nil {
+			m.Issues++
+			if open {
+				m.OpenIssues++
+			}
+		} else {
+			m.PullRequests++
+			if open {
+				m.OpenPullRequests++
+			}
+		}
+	}
+	m.ClosedPullRequests, m.MergedPullRequests = mergeCounts(p)
+	m.MergeRatio = ratio(m.MergedPullRequests, m.ClosedPullRequests)
+	m.CommunityMerged, m.CommunityClosedUnmerged, m.CommunityOpen = communityPRCounts(p)
+	m.CommunityMergeRatio = ratio(m.CommunityMerged, m.CommunityMerged+m.CommunityClosedUnmerged)
+	for _, s := range p.Sources() {
+		m.Repos = append(m.Repos, ComputeMetrics(s))
+	}
+	return m
+}
@@ -0,0 +1,84 @@
+package roachpulse
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// labelAliases maps label names which have since been renamed to their
+// current names. It is set from -label-alias.
+var labelAliases = make(map[string]string)
+
+// LoadLabelAliases reads label renames from path, one "old=new" pair per
+// line. Blank lines and lines starting with '#' are ignored.
+func LoadLabelAliases(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("%s:%d: expected old=new, got %q", path, n+1, line)
+		}
+		old, cur := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if old == "" || cur == "" {
+			return fmt.Errorf("%s:%d: expected old=new, got %q", path, n+1, line)
+		}
+		labelAliases[old] = cur
+	}
+	return nil
+}
+
+// canonicalLabel returns the current name of the named label. Labels which
+// have not been renamed are returned unchanged.
+func canonicalLabel(name string) string {
+	if cur, ok := labelAliases[name]; ok {
+		return cur
+	}
+	return name
+}
+
+// AliasLabels renames the labels on every issue, and in labeled and
+// unlabeled timeline events, to their current names, dropping any duplicate
+// this produces. It only rewrites the in-memory issues, so it must be called
+// after the project has been refreshed and saved.
+func (p *Project) AliasLabels() {
+	if len(labelAliases) == 0 {
+		return
+	}
+	for _, i := range p.issues {
+		var labels []github.Label
+		seen := make(map[string]bool)
+		for _, l := range i.Labels {
+			name := canonicalLabel(l.GetName())
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if name != l.GetName() {
+				l.Name = github.String(name)
+			}
+			labels = append(labels, l)
+		}
+		i.Labels = labels
+
+		for _, t := range i.Timeline {
+			if t.Label == nil {
+				continue
+			}
+			if name := canonicalLabel(t.Label.GetName()); name != t.Label.GetName() {
+				l := *t.Label
+				l.Name = github.String(name)
+				t.Label = &l
+			}
+		}
+	}
+}
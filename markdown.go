@@ -0,0 +1,80 @@
+package roachpulse
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// markdownStaleDays is the inactivity threshold for the stale issues in
+// the Markdown report when -stale is not set.
+const markdownStaleDays = 30
+
+// mdEscape escapes s for use in a Markdown table cell.
+func mdEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// WriteMarkdownReport writes a Markdown summary of p suitable for pasting
+// into a GitHub comment or chat message, listing up to top stale and most
+// reacted issues. Issues are stale after staleDays without activity, or
+// markdownStaleDays if staleDays is not positive. The figures come from the
+// same functions as -json, -stale and -reactions.
+func WriteMarkdownReport(w io.Writer, p *Project, staleDays, top int) error {
+	now := time.Now()
+	m := ComputeMetrics(p)
+	days := staleDays
+	if days <= 0 {
+		days = markdownStaleDays
+	}
+	staleIssues, _ := staleItems(p, days, now)
+	if len(staleIssues) > top {
+		staleIssues = staleIssues[:top]
+	}
+	reacted := mostReacted(p, top)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", m.Project)
+	fmt.Fprintf(&b, "_Generated %s._\n\n", now.Format("2006-01-02"))
+
+	fmt.Fprintf(&b, "## Summary\n\n")
+	fmt.Fprintf(&b, "| | Open | Closed | Total |\n|---|---:|---:|---:|\n")
+	fmt.Fprintf(&b, "| Issues | %d | %d | %d |\n", m.OpenIssues, m.Issues-m.OpenIssues, m.Issues)
+	fmt.Fprintf(&b, "| Pull requests | %d | %d | %d |\n\n",
+		m.OpenPullRequests, m.PullRequests-m.OpenPullRequests, m.PullRequests)
+	fmt.Fprintf(&b, "| Metric | Value |\n|---|---:|\n")
+	fmt.Fprintf(&b, "| PR merge ratio | %0.1f%% (%d/%d) |\n",
+		100*m.MergeRatio, m.MergedPullRequests, m.ClosedPullRequests)
+	fmt.Fprintf(&b, "| Community PR merge ratio | %0.1f%% |\n", 100*m.CommunityMergeRatio)
+	fmt.Fprintf(&b, "| Mean PR age | %0.1fd |\n", m.PRAgeDays.Mean)
+	fmt.Fprintf(&b, "| Median PR age | %dd |\n", m.PRAgeDays.Median)
+	fmt.Fprintf(&b, "| Mean issue close time | %0.1fd |\n", m.IssueCloseDays.Mean)
+	fmt.Fprintf(&b, "| Median issue close time | %dd |\n\n", m.IssueCloseDays.Median)
+
+	fmt.Fprintf(&b, "## Stale issues\n\n")
+	if len(staleIssues) == 0 {
+		fmt.Fprintf(&b, "No open issues without activity in %d days.\n\n", days)
+	} else {
+		fmt.Fprintf(&b, "Open issues without activity in %d days, oldest first.\n\n", days)
+		fmt.Fprintf(&b, "| Issue | Last activity | Title |\n|---|---|---|\n")
+		for _, i := range staleIssues {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", i.Ref(),
+				i.lastActivity().Format("2006-01-02"), mdEscape(i.GetTitle()))
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+
+	fmt.Fprintf(&b, "## Most reacted issues\n\n")
+	if len(reacted) == 0 {
+		fmt.Fprintf(&b, "No open issues with reactions.\n")
+	} else {
+		fmt.Fprintf(&b, "| Issue | Reactions | Title |\n|---|---:|---|\n")
+		for _, i := range reacted {
+			fmt.Fprintf(&b, "| %s | %d | %s |\n", i.Ref(), i.positiveReactions(), mdEscape(i.GetTitle()))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
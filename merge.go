@@ -0,0 +1,88 @@
+package roachpulse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Merge returns a project combining the issues of projects, so that metrics
+// can be computed across several repositories. Each issue records the
+// repository it came from in Source, and the original projects remain
+// available through Sources for per-repository breakdowns. The merged
+// project cannot be loaded, saved or refreshed.
+func Merge(projects []*Project) *Project {
+	m := NewProject("", "", "")
+	m.sources = projects
+	for _, p := range projects {
+		name := p.Name()
+		for _, num := range p.sortedIssues() {
+			i := p.issues[num]
+			i.Source = name
+			// Issue numbers are only unique within a repository, so the
+			// merged issues are keyed by their position instead.
+			m.issues[len(m.issues)+1] = i
+		}
+		for login := range p.team {
+			m.team[login] = true
+		}
+		for id, u := range p.users {
+			m.users[id] = u
+		}
+		for id, ms := range p.milestones {
+			m.milestones[id] = ms
+		}
+		for id, r := range p.repos {
+			m.repos[id] = r
+		}
+		if m.RefreshedAt.IsZero() || p.RefreshedAt.Before(m.RefreshedAt) {
+			m.RefreshedAt = p.RefreshedAt
+		}
+	}
+	return m
+}
+
+// Name returns the project's owner/repo, or a comma-separated list of them
+// for a merged project.
+func (p *Project) Name() string {
+	if len(p.sources) == 0 {
+		return fmt.Sprintf("%s/%s", p.Owner, p.Repo)
+	}
+	names := make([]string, len(p.sources))
+	for j, s := range p.sources {
+		names[j] = s.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// Sources returns the projects merged into p, restricted to the issues p
+// still contains, or nil if p is not a merged project.
+func (p *Project) Sources() []*Project {
+	if len(p.sources) == 0 {
+		return nil
+	}
+	included := make(map[*Issue]bool, len(p.issues))
+	for _, i := range p.issues {
+		included[i] = true
+	}
+	sources := make([]*Project, len(p.sources))
+	for j, s := range p.sources {
+		sources[j] = s.Filter(func(i *Issue) bool { return included[i] })
+	}
+	return sources
+}
+
+// Ref returns the GitHub reference to the issue: "#123", or
+// "owner/repo#123" for an issue of a merged project.
+func (i *Issue) Ref() string {
+	return i.Source + "#" + strconv.Itoa(i.GetNumber())
+}
+
+// id returns the issue's number for use in reports, qualified by its
+// repository for an issue of a merged project.
+func (i *Issue) id() string {
+	if i.Source == "" {
+		return strconv.Itoa(i.GetNumber())
+	}
+	return i.Ref()
+}
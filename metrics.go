@@ -0,0 +1,2013 @@
+package roachpulse
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codahale/hdrhistogram"
+	"github.com/google/go-github/github"
+)
+
+// HistMetrics maps the names accepted by -hist-metric to the functions
+// computing the corresponding histogram.
+var HistMetrics = map[string]func(p *Project) *hdrhistogram.Histogram{
+	"pr-age":         PRAgeHistogram,
+	"issue-close":    IssueCloseHistogram,
+	"first-response": FirstResponseHistogram,
+	"review-latency": ReviewLatencyHistogram,
+	"triage":         TriageHistogram,
+	"open-age": func(p *Project) *hdrhistogram.Histogram {
+		return OpenAgeHistogram(p, time.Now())
+	},
+}
+
+// CloseTimes returns the time taken to close each closed issue for which
+// include returns true.
+func CloseTimes(p *Project, include func(i *Issue) bool) []time.Duration {
+	var d []time.Duration
+	for _, i := range p.issues {
+		if !include(i) {
+			continue
+		}
+		if i.ClosedAt == nil || i.CreatedAt == nil {
+			continue
+		}
+		d = append(d, i.ClosedAt.Sub(*i.CreatedAt))
+	}
+	return d
+}
+
+func IsPR(i *Issue) bool {
+	return i.PullRequestLinks != nil
+}
+
+func IsIssue(i *Issue) bool {
+	return i.PullRequestLinks == nil
+}
+
+// PRAgeHistogram records the age in days of closed pull requests.
+func PRAgeHistogram(p *Project) *hdrhistogram.Histogram {
+	return daysHistogram(CloseTimes(p, IsPR))
+}
+
+// IssueCloseHistogram records the time in days taken to close issues,
+// excluding pull requests.
+func IssueCloseHistogram(p *Project) *hdrhistogram.Histogram {
+	return daysHistogram(CloseTimes(p, IsIssue))
+}
+
+// OpenAgeHistogram records the age in days at now of issues which are still
+// open, excluding pull requests.
+func OpenAgeHistogram(p *Project, now time.Time) *hdrhistogram.Histogram {
+	var d []time.Duration
+	for _, i := range p.issues {
+		if i.ClosedAt != nil || i.PullRequestLinks != nil || i.CreatedAt == nil {
+			continue
+		}
+		d = append(d, now.Sub(*i.CreatedAt))
+	}
+	return daysHistogram(d)
+}
+
+// merged returns true if the issue is a pull request that was merged.
+func (i *Issue) merged() bool {
+	for _, t := range i.Timeline {
+		if t.GetEvent() == "merged" {
+			return true
+		}
+	}
+	return false
+}
+
+// mergedAt returns the time the pull request was merged, or the zero time
+// if it was not merged.
+func (i *Issue) mergedAt() time.Time {
+	for _, t := range i.Timeline {
+		if t.GetEvent() == "merged" {
+			return t.GetCreatedAt()
+		}
+	}
+	return time.Time{}
+}
+
+// approvers returns the number of distinct users that approved the pull
+// request.
+func (i *Issue) approvers() int {
+	seen := make(map[int]bool)
+	for _, r := range i.Reviews {
+		if r.GetState() != "APPROVED" {
+			continue
+		}
+		if id := r.User.GetID(); id != 0 {
+			seen[id] = true
+		}
+	}
+	return len(seen)
+}
+
+// printCounts prints the number and percentage of items in each bucket,
+// where counts[n] is the number of items with n occurrences. The last bucket
+// is assumed to also hold anything larger.
+func printCounts(counts []int) {
+	var total int
+	for _, c := range counts {
+		total += c
+	}
+	for n, c := range counts {
+		label := strconv.Itoa(n)
+		if n == len(counts)-1 {
+			label += "+"
+		}
+		var pct float64
+		if total > 0 {
+			pct = 100 * float64(c) / float64(total)
+		}
+		fmt.Printf("  %2s: %5d (%4.1f%%)\n", label, c, pct)
+	}
+}
+
+// ReportApprovals prints the distribution of the number of distinct
+// approvers on merged pull requests, followed by the merged pull requests
+// which were never approved.
+func ReportApprovals(p *Project) {
+	const maxBucket = 3
+	var counts [maxBucket + 1]int
+	var unapproved []*Issue
+	var total int
+	for _, num := range p.sortedIssues() {
+		i := p.issues[num]
+		if i.PullRequestLinks == nil || !i.merged() {
+			continue
+		}
+		n := i.approvers()
+		if n == 0 {
+			unapproved = append(unapproved, i)
+		}
+		if n > maxBucket {
+			n = maxBucket
+		}
+		counts[n]++
+		total++
+	}
+
+	fmt.Printf("approvals (%d merged pull requests)\n", total)
+	printCounts(counts[:])
+	if len(unapproved) > 0 {
+		fmt.Printf("merged without approval:\n")
+		for _, i := range unapproved {
+			fmt.Printf("  %s: %s\n", i.id(), i.GetTitle())
+		}
+	}
+}
+
+// quarter returns the calendar quarter containing t, e.g. "2017-Q3".
+func quarter(t time.Time) string {
+	return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+}
+
+// reopenedWithin returns the time the issue was first closed and whether any
+// close was followed by a reopen within window. The returned time is zero if
+// the timeline contains no close event.
+func (i *Issue) reopenedWithin(window time.Duration) (firstClose time.Time, reopened bool) {
+	var lastClose time.Time
+	for _, t := range i.Timeline {
+		switch t.GetEvent() {
+		case "closed":
+			lastClose = t.GetCreatedAt()
+			if firstClose.IsZero() {
+				firstClose = lastClose
+			}
+		case "reopened":
+			if !lastClose.IsZero() && t.GetCreatedAt().Sub(lastClose) <= window {
+				reopened = true
+			}
+		}
+	}
+	return firstClose, reopened
+}
+
+// ReportReopenRate prints the fraction of closed issues that were reopened
+// within window of being closed, overall and by the quarter of the first
+// close.
+func ReportReopenRate(p *Project, window time.Duration) {
+	type rate struct {
+		closed, reopened int
+	}
+	var total rate
+	byQuarter := make(map[string]*rate)
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil {
+			continue
+		}
+		closed, reopened := i.reopenedWithin(window)
+		if closed.IsZero() {
+			continue
+		}
+		q := byQuarter[quarter(closed)]
+		if q == nil {
+			q = &rate{}
+			byQuarter[quarter(closed)] = q
+		}
+		q.closed++
+		total.closed++
+		if reopened {
+			q.reopened++
+			total.reopened++
+		}
+	}
+
+	pct := func(r *rate) float64 {
+		if r.closed == 0 {
+			return 0
+		}
+		return 100 * float64(r.reopened) / float64(r.closed)
+	}
+	fmt.Printf("reopened within %.0fd: %d/%d (%.1f%%)\n",
+		window.Hours()/24, total.reopened, total.closed, pct(&total))
+	quarters := make([]string, 0, len(byQuarter))
+	for q := range byQuarter {
+		quarters = append(quarters, q)
+	}
+	sort.Strings(quarters)
+	for _, q := range quarters {
+		r := byQuarter[q]
+		fmt.Printf("  %s: %d/%d (%.1f%%)\n", q, r.reopened, r.closed, pct(r))
+	}
+}
+
+// reopenCount returns the number of times the issue was reopened.
+func (i *Issue) reopenCount() int {
+	var n int
+	for _, t := range i.Timeline {
+		if t.GetEvent() == "reopened" {
+			n++
+		}
+	}
+	return n
+}
+
+// ReportReopens prints the fraction of closed issues that were ever
+// reopened and lists the issues reopened most often.
+func ReportReopens(p *Project, top int) {
+	var closed int
+	var reopened []*Issue
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil {
+			continue
+		}
+		n := i.reopenCount()
+		if i.ClosedAt != nil || n > 0 {
+			closed++
+		}
+		if n > 0 {
+			reopened = append(reopened, i)
+		}
+	}
+	sort.Slice(reopened, func(a, b int) bool {
+		if ra, rb := reopened[a].reopenCount(), reopened[b].reopenCount(); ra != rb {
+			return ra > rb
+		}
+		return reopened[a].GetNumber() < reopened[b].GetNumber()
+	})
+
+	fmt.Printf("ever reopened: %d/%d (%0.1f%%)\n", len(reopened), closed,
+		100*ratio(len(reopened), closed))
+	if len(reopened) > top {
+		reopened = reopened[:top]
+	}
+	for _, i := range reopened {
+		fmt.Printf("  %s: %d reopens: %s\n", i.id(), i.reopenCount(), i.GetTitle())
+	}
+}
+
+// bots holds the lowercased logins of accounts which are treated as bots
+// even though GitHub does not type them as such. It is set from -bots.
+var bots = make(map[string]bool)
+
+// ParseBots parses a comma-separated list of logins into bots.
+func ParseBots(s string) {
+	for _, login := range strings.Split(s, ",") {
+		if login = strings.TrimSpace(login); login != "" {
+			bots[strings.ToLower(login)] = true
+		}
+	}
+}
+
+// IsBot returns true if u is a bot account.
+func IsBot(u *github.User) bool {
+	return u.GetType() == "Bot" || strings.HasSuffix(u.GetLogin(), "[bot]") ||
+		bots[strings.ToLower(u.GetLogin())]
+}
+
+// isoWeek returns the ISO 8601 week containing t, e.g. "2017-W09".
+func isoWeek(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// BucketKey returns the key of the bucket containing t for the given mode:
+// "week" (e.g. 2017-W09), "month" (e.g. 2017-03) or "quarter" (e.g.
+// 2017-Q1). Keys of the same mode sort chronologically. An empty string is
+// returned for an unknown mode.
+func BucketKey(t time.Time, mode string) string {
+	switch mode {
+	case "week":
+		return isoWeek(t)
+	case "month":
+		return t.Format("2006-01")
+	case "quarter":
+		return quarter(t)
+	}
+	return ""
+}
+
+// bucketRange returns the keys of every bucket from the one containing
+// first to the one containing last, in order, including buckets in which
+// nothing happened.
+func bucketRange(first, last time.Time, mode string) []string {
+	var keys []string
+	// Walk the range a day at a time, which visits every bucket whatever
+	// the mode.
+	for t := first; ; t = t.AddDate(0, 0, 1) {
+		if t.After(last) {
+			t = last
+		}
+		if k := BucketKey(t, mode); len(keys) == 0 || keys[len(keys)-1] != k {
+			keys = append(keys, k)
+		}
+		if t.Equal(last) {
+			return keys
+		}
+	}
+}
+
+// addBusinessDays returns t advanced by n weekdays.
+func addBusinessDays(t time.Time, n int) time.Time {
+	for n > 0 {
+		t = t.AddDate(0, 0, 1)
+		if wd := t.Weekday(); wd != time.Saturday && wd != time.Sunday {
+			n--
+		}
+	}
+	return t
+}
+
+// firstLabeled returns the time the first label was applied to the issue,
+// or the zero time if the issue was never labeled.
+func (i *Issue) firstLabeled() time.Time {
+	for _, t := range i.Timeline {
+		if t.GetEvent() == "labeled" {
+			return t.GetCreatedAt()
+		}
+	}
+	return time.Time{}
+}
+
+// WriteTriageCoverageCSV writes, for each week, the number of issues filed
+// and how many of them were labeled within sla business days. Issues whose
+// deadline has not yet passed as of now are only counted once triaged.
+func WriteTriageCoverageCSV(w io.Writer, p *Project, sla int, now time.Time) error {
+	type coverage struct {
+		filed, triaged int
+	}
+	weeks := make(map[string]*coverage)
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil || i.CreatedAt == nil || IsBot(i.User) {
+			continue
+		}
+		deadline := addBusinessDays(*i.CreatedAt, sla)
+		labeled := i.firstLabeled()
+		triaged := !labeled.IsZero() && !labeled.After(deadline)
+		if !triaged && deadline.After(now) {
+			continue
+		}
+		c := weeks[isoWeek(*i.CreatedAt)]
+		if c == nil {
+			c = &coverage{}
+			weeks[isoWeek(*i.CreatedAt)] = c
+		}
+		c.filed++
+		if triaged {
+			c.triaged++
+		}
+	}
+
+	keys := make([]string, 0, len(weeks))
+	for k := range weeks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"week", "filed", "triaged", "coverage"}); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		c := weeks[k]
+		err := cw.Write([]string{
+			k,
+			strconv.Itoa(c.filed),
+			strconv.Itoa(c.triaged),
+			strconv.FormatFloat(float64(c.triaged)/float64(c.filed), 'f', 3, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// HasLabel returns true if the issue currently carries the named label, or
+// the label it was renamed to.
+func (i *Issue) HasLabel(name string) bool {
+	name = canonicalLabel(name)
+	for _, l := range i.Labels {
+		if l.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasLabels returns true if the issue carries every named label, or with
+// matchAny set, at least one of them. Like HasLabel, it accepts labels
+// which have since been renamed.
+func (i *Issue) HasLabels(names []string, matchAny bool) bool {
+	for _, name := range names {
+		if i.HasLabel(name) == matchAny {
+			return matchAny
+		}
+	}
+	return !matchAny
+}
+
+// labelDuration returns the total time the named label was applied to the
+// issue, replaying labeled and unlabeled events in timestamp order. A label
+// that is still applied is counted until the issue was closed, or until now
+// if it is still open.
+func (i *Issue) labelDuration(label string, now time.Time) time.Duration {
+	label = canonicalLabel(label)
+	var events []*github.Timeline
+	for _, t := range i.Timeline {
+		switch t.GetEvent() {
+		case "labeled", "unlabeled":
+			if t.Label.GetName() == label {
+				events = append(events, t)
+			}
+		}
+	}
+	sort.SliceStable(events, func(a, b int) bool {
+		return events[a].GetCreatedAt().Before(events[b].GetCreatedAt())
+	})
+
+	var total time.Duration
+	var applied time.Time
+	for _, t := range events {
+		if t.GetEvent() == "labeled" {
+			if applied.IsZero() {
+				applied = t.GetCreatedAt()
+			}
+			continue
+		}
+		if !applied.IsZero() {
+			total += t.GetCreatedAt().Sub(applied)
+			applied = time.Time{}
+		}
+	}
+	if !applied.IsZero() {
+		end := now
+		if i.ClosedAt != nil {
+			end = *i.ClosedAt
+		}
+		if end.After(applied) {
+			total += end.Sub(applied)
+		}
+	}
+	return total
+}
+
+// LabelDurationHistogram returns a histogram of the hours each issue, other
+// than pull requests, spent carrying label. Issues which never carried it
+// are not recorded.
+func LabelDurationHistogram(p *Project, label string, now time.Time) *hdrhistogram.Histogram {
+	var durations []time.Duration
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil {
+			continue
+		}
+		if d := i.labelDuration(label, now); d > 0 {
+			durations = append(durations, d)
+		}
+	}
+	return hoursHistogram(durations)
+}
+
+// ReportLabelTime prints the distribution of time issues spent carrying
+// label along with the open issues that have been waiting the longest.
+func ReportLabelTime(p *Project, label string, now time.Time) {
+	type stuck struct {
+		i *Issue
+		d time.Duration
+	}
+	var open []stuck
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil || i.ClosedAt != nil || !i.HasLabel(label) {
+			continue
+		}
+		if d := i.labelDuration(label, now); d > 0 {
+			open = append(open, stuck{i: i, d: d})
+		}
+	}
+
+	h := LabelDurationHistogram(p, label, now)
+	if h.TotalCount() == 0 {
+		fmt.Printf("%s: no data\n", label)
+		return
+	}
+	fmt.Printf("%s (%d issues): mean=%0.1fd median=%0.1fd %s\n", label, h.TotalCount(),
+		h.Mean()/24, float64(h.ValueAtQuantile(50))/24,
+		formatQuantiles(h, func(hours int64) string {
+			return fmt.Sprintf("%0.1fd", float64(hours)/24)
+		}))
+
+	sort.Slice(open, func(a, b int) bool { return open[a].d > open[b].d })
+	const top = 10
+	if len(open) > top {
+		open = open[:top]
+	}
+	for _, s := range open {
+		fmt.Printf("  %s: %0.1fd %s\n", s.i.id(), s.d.Hours()/24, s.i.GetTitle())
+	}
+}
+
+// mergeCounts returns the number of closed pull requests and how many of
+// them were merged.
+func mergeCounts(p *Project) (closed, merged int) {
+	for _, i := range p.issues {
+		if i.PullRequestLinks == nil || i.ClosedAt == nil {
+			continue
+		}
+		closed++
+		if i.merged() {
+			merged++
+		}
+	}
+	return closed, merged
+}
+
+// SummaryLine returns a one line digest of the headline metrics, suitable
+// for status bars and email subjects.
+func SummaryLine(p *Project, now time.Time) string {
+	var open, closedWeek int
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil {
+			continue
+		}
+		if i.ClosedAt == nil {
+			open++
+		} else if now.Sub(*i.ClosedAt) <= 7*24*time.Hour {
+			closedWeek++
+		}
+	}
+	closeDays := summarize(IssueCloseHistogram(p))
+	var mergeRate float64
+	if closed, merged := mergeCounts(p); closed > 0 {
+		mergeRate = 100 * float64(merged) / float64(closed)
+	}
+	return fmt.Sprintf("open=%d closed-7d=%d mean-close=%.0fd median-close=%dd merge-rate=%.0f%%",
+		open, closedWeek, closeDays.Mean, closeDays.Median, mergeRate)
+}
+
+// isTestFile returns true if name, or any of its directories, matches one of
+// patterns.
+func isTestFile(name string, patterns []string) bool {
+	for _, part := range strings.Split(name, "/") {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, part); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReportTestPRs classifies merged pull requests by whether they changed test
+// files, code files or both. Pull requests without cached file data are
+// skipped.
+func ReportTestPRs(p *Project, patterns []string) {
+	var both, codeOnly, testOnly int
+	for _, i := range p.issues {
+		if i.PullRequestLinks == nil || i.Files == nil || !i.merged() {
+			continue
+		}
+		var tests, code bool
+		for _, f := range i.Files {
+			if isTestFile(f.GetFilename(), patterns) {
+				tests = true
+			} else {
+				code = true
+			}
+		}
+		switch {
+		case tests && code:
+			both++
+		case code:
+			codeOnly++
+		case tests:
+			testOnly++
+		}
+	}
+
+	fmt.Printf("tests (%d merged pull requests)\n", both+codeOnly+testOnly)
+	fmt.Printf("  code+test: %d\n", both)
+	fmt.Printf("  code-only: %d\n", codeOnly)
+	fmt.Printf("  test-only: %d\n", testOnly)
+	var pct float64
+	if both+codeOnly > 0 {
+		pct = 100 * float64(both) / float64(both+codeOnly)
+	}
+	fmt.Printf("code changes with tests: %0.1f%%\n", pct)
+}
+
+// ReportBreadth prints, for each non-bot user, the number of distinct issues
+// they acted on since the specified time alongside their total number of
+// actions, ordered by the number of distinct issues. If the project has a
+// team, only maintainers are included.
+func ReportBreadth(p *Project, since time.Time) {
+	type engagement struct {
+		user    *github.User
+		issues  int
+		actions int
+	}
+	users := make(map[int]*engagement)
+	for _, i := range p.issues {
+		seen := make(map[int]bool)
+		for _, t := range i.Timeline {
+			if t.Actor == nil || IsBot(t.Actor) || t.GetCreatedAt().Before(since) {
+				continue
+			}
+			if len(p.team) > 0 && !p.isMaintainer(t.Actor) {
+				continue
+			}
+			id := t.Actor.GetID()
+			e := users[id]
+			if e == nil {
+				e = &engagement{user: t.Actor}
+				users[id] = e
+			}
+			e.actions++
+			if !seen[id] {
+				seen[id] = true
+				e.issues++
+			}
+		}
+	}
+
+	sorted := make([]*engagement, 0, len(users))
+	for _, e := range users {
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(a, b int) bool {
+		if sorted[a].issues != sorted[b].issues {
+			return sorted[a].issues > sorted[b].issues
+		}
+		return sorted[a].user.GetLogin() < sorted[b].user.GetLogin()
+	})
+
+	fmt.Printf("breadth since %s\n", since.Format("2006-01-02"))
+	fmt.Printf("  %-20s %7s %7s %7s\n", "user", "issues", "actions", "depth")
+	for _, e := range sorted {
+		fmt.Printf("  %-20s %7d %7d %7.1f\n", e.user.GetLogin(), e.issues, e.actions,
+			float64(e.actions)/float64(e.issues))
+	}
+}
+
+// ReportLabelAges prints, for each label, the number of open issues carrying
+// it along with the mean and quantiles of their age in days.
+func ReportLabelAges(p *Project, now time.Time) {
+	ages := make(map[string][]time.Duration)
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil || i.ClosedAt != nil || i.CreatedAt == nil {
+			continue
+		}
+		for _, l := range i.Labels {
+			ages[l.GetName()] = append(ages[l.GetName()], now.Sub(*i.CreatedAt))
+		}
+	}
+
+	labels := make([]string, 0, len(ages))
+	for l := range ages {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(a, b int) bool {
+		if na, nb := len(ages[labels[a]]), len(ages[labels[b]]); na != nb {
+			return na > nb
+		}
+		return labels[a] < labels[b]
+	})
+
+	fmt.Printf("open issue age by label\n")
+	fmt.Printf("  %-30s %6s %8s", "label", "open", "mean")
+	for _, q := range Quantiles {
+		fmt.Printf(" %8s", quantileName(q))
+	}
+	fmt.Printf("\n")
+	for _, l := range labels {
+		h := daysHistogram(ages[l])
+		fmt.Printf("  %-30s %6d %7.1fd", l, h.TotalCount(), h.Mean())
+		for _, q := range Quantiles {
+			fmt.Printf(" %7dd", h.ValueAtQuantile(q))
+		}
+		fmt.Printf("\n")
+	}
+}
+
+// unassigned is the name under which ReportWorkload counts issues without
+// an assignee.
+const unassigned = "(unassigned)"
+
+// ReportWorkload prints, for each assignee, the number of open issues
+// assigned to them and the mean age of those issues, so that people holding
+// a lot of old work stand out. An issue with several assignees counts
+// towards each of them.
+func ReportWorkload(p *Project, now time.Time) {
+	ages := make(map[string][]time.Duration)
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil || i.ClosedAt != nil || i.CreatedAt == nil {
+			continue
+		}
+		age := now.Sub(*i.CreatedAt)
+		if len(i.Assignees) == 0 {
+			ages[unassigned] = append(ages[unassigned], age)
+			continue
+		}
+		for _, u := range i.Assignees {
+			ages[u.GetLogin()] = append(ages[u.GetLogin()], age)
+		}
+	}
+
+	logins := make([]string, 0, len(ages))
+	for l := range ages {
+		logins = append(logins, l)
+	}
+	sort.Slice(logins, func(a, b int) bool {
+		if na, nb := len(ages[logins[a]]), len(ages[logins[b]]); na != nb {
+			return na > nb
+		}
+		return logins[a] < logins[b]
+	})
+
+	fmt.Printf("open issues by assignee\n")
+	fmt.Printf("  %-20s %6s %8s %8s\n", "assignee", "open", "mean", "median")
+	for _, l := range logins {
+		h := daysHistogram(ages[l])
+		fmt.Printf("  %-20s %6d %7.1fd %7dd\n", l, h.TotalCount(), h.Mean(), h.ValueAtQuantile(50))
+	}
+}
+
+// conflictRebases returns the number of times the head branch of the pull
+// request was force pushed after its base branch changed or was itself
+// force pushed, which is when a rebase to resolve conflicts is needed.
+// Ordinary amend or squash pushes with no base change in between are not
+// counted, and several pushes after one base change count once.
+func (i *Issue) conflictRebases() int {
+	var events []*github.Timeline
+	for _, t := range i.Timeline {
+		switch t.GetEvent() {
+		case "base_ref_changed", "base_ref_force_pushed", "head_ref_force_pushed":
+			events = append(events, t)
+		}
+	}
+	sort.SliceStable(events, func(a, b int) bool {
+		return events[a].GetCreatedAt().Before(events[b].GetCreatedAt())
+	})
+
+	var n int
+	var baseChanged bool
+	for _, t := range events {
+		if t.GetEvent() != "head_ref_force_pushed" {
+			baseChanged = true
+			continue
+		}
+		if baseChanged {
+			n++
+			baseChanged = false
+		}
+	}
+	return n
+}
+
+// ReportRebases prints the share of closed pull requests which were rebased
+// after a base change during their lifetime, along with the distribution of
+// such rebases.
+func ReportRebases(p *Project) {
+	const maxBucket = 3
+	var counts [maxBucket + 1]int
+	var total, rebased int
+	for _, i := range p.issues {
+		if i.PullRequestLinks == nil || i.ClosedAt == nil {
+			continue
+		}
+		n := i.conflictRebases()
+		if n > 0 {
+			rebased++
+		}
+		if n > maxBucket {
+			n = maxBucket
+		}
+		counts[n]++
+		total++
+	}
+
+	var pct float64
+	if total > 0 {
+		pct = 100 * float64(rebased) / float64(total)
+	}
+	fmt.Printf("rebased after a base change: %d/%d closed pull requests (%0.1f%%)\n", rebased, total, pct)
+	printCounts(counts[:])
+}
+
+// CountIssues returns the number of issues for which include returns true.
+func CountIssues(p *Project, include func(i *Issue) bool) int {
+	var n int
+	for _, i := range p.issues {
+		if include(i) {
+			n++
+		}
+	}
+	return n
+}
+
+// trailingPings returns the number of comments at the end of the issue's
+// timeline made by the issue author or by bots, along with the time of the
+// last one. Comment bodies are not cached so their content is not
+// considered.
+func (i *Issue) trailingPings() (n int, last time.Time) {
+	author := i.User.GetID()
+	for j := len(i.Timeline) - 1; j >= 0; j-- {
+		t := i.Timeline[j]
+		if t.GetEvent() != "commented" {
+			continue
+		}
+		if t.Actor.GetID() != author && !IsBot(t.Actor) {
+			break
+		}
+		if n == 0 {
+			last = t.GetCreatedAt()
+		}
+		n++
+	}
+	return n, last
+}
+
+// ReportPings lists open issues whose last minPings or more comments were
+// all made by the author or by bots, i.e. issues which look active but have
+// not received a response.
+func ReportPings(p *Project, minPings int) {
+	type ping struct {
+		i    *Issue
+		n    int
+		last time.Time
+	}
+	var found []ping
+	for _, i := range p.issues {
+		if i.ClosedAt != nil {
+			continue
+		}
+		if n, last := i.trailingPings(); n >= minPings {
+			found = append(found, ping{i: i, n: n, last: last})
+		}
+	}
+	sort.Slice(found, func(a, b int) bool {
+		return found[a].last.Before(found[b].last)
+	})
+
+	fmt.Printf("needs-attention pings (%d)\n", len(found))
+	for _, f := range found {
+		fmt.Printf("  %s: %d pings, last %s: %s\n", f.i.id(), f.n,
+			f.last.Format("2006-01-02"), f.i.GetTitle())
+	}
+}
+
+// closer returns the user that last closed the issue.
+func (i *Issue) closer() *github.User {
+	for j := len(i.Timeline) - 1; j >= 0; j-- {
+		if t := i.Timeline[j]; t.GetEvent() == "closed" {
+			return t.Actor
+		}
+	}
+	return i.ClosedBy
+}
+
+// humanComments returns the number of comments on the issue's timeline made by
+// users other than bots.
+func (i *Issue) humanComments() int {
+	var n int
+	for _, t := range i.Timeline {
+		if t.GetEvent() == "commented" && !IsBot(t.Actor) {
+			n++
+		}
+	}
+	return n
+}
+
+// ReportSilentCloses prints the share of closed issues which were closed
+// without any non-bot comments, split by whether the author or someone else
+// closed them, along with a few examples of the latter.
+func ReportSilentCloses(p *Project) {
+	const examples = 10
+	var closed, byAuthor, byOther int
+	var silent []*Issue
+	for _, num := range p.sortedIssues() {
+		i := p.issues[num]
+		if i.PullRequestLinks != nil || i.ClosedAt == nil {
+			continue
+		}
+		closed++
+		if i.humanComments() > 0 {
+			continue
+		}
+		if c := i.closer(); c != nil && c.GetID() == i.User.GetID() {
+			byAuthor++
+		} else {
+			byOther++
+			silent = append(silent, i)
+		}
+	}
+
+	pct := func(n int) float64 {
+		if closed == 0 {
+			return 0
+		}
+		return 100 * float64(n) / float64(closed)
+	}
+	fmt.Printf("closed without comment: %d/%d (%0.1f%%)\n", byAuthor+byOther, closed, pct(byAuthor+byOther))
+	fmt.Printf("  by author: %d (%0.1f%%)\n", byAuthor, pct(byAuthor))
+	fmt.Printf("  by others: %d (%0.1f%%)\n", byOther, pct(byOther))
+	if len(silent) > examples {
+		silent = silent[len(silent)-examples:]
+	}
+	for _, i := range silent {
+		fmt.Printf("  %s: %s\n", i.id(), i.GetTitle())
+	}
+}
+
+// ParseLabelSets parses semicolon-separated sets of comma-separated labels,
+// e.g. "C-bug,C-enhancement;S-1,S-2".
+func ParseLabelSets(s string) [][]string {
+	var sets [][]string
+	for _, set := range strings.Split(s, ";") {
+		var labels []string
+		for _, l := range strings.Split(set, ",") {
+			if l = strings.TrimSpace(l); l != "" {
+				labels = append(labels, l)
+			}
+		}
+		if len(labels) > 1 {
+			sets = append(sets, labels)
+		}
+	}
+	return sets
+}
+
+// conflictingLabels returns the labels on the issue that belong to the same
+// set as another of its labels.
+func (i *Issue) conflictingLabels(sets [][]string) []string {
+	var conflicts []string
+	for _, set := range sets {
+		var present []string
+		for _, l := range set {
+			if i.HasLabel(l) {
+				present = append(present, l)
+			}
+		}
+		if len(present) > 1 {
+			conflicts = append(conflicts, present...)
+		}
+	}
+	return conflicts
+}
+
+// ReportLabelConflicts lists the issues carrying more than one label from
+// any of the mutually exclusive sets.
+func ReportLabelConflicts(p *Project, sets [][]string) {
+	var n int
+	for _, num := range p.sortedIssues() {
+		i := p.issues[num]
+		if c := i.conflictingLabels(sets); len(c) > 0 {
+			if n == 0 {
+				fmt.Printf("conflicting labels\n")
+			}
+			n++
+			fmt.Printf("  %s: %s: %s\n", i.id(), strings.Join(c, ","), i.GetTitle())
+		}
+	}
+	if n == 0 {
+		fmt.Printf("conflicting labels: none\n")
+	}
+}
+
+// hasLabelPrefix returns true if the issue carries a label whose name
+// starts with prefix.
+func (i *Issue) hasLabelPrefix(prefix string) bool {
+	for _, l := range i.Labels {
+		if strings.HasPrefix(l.GetName(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportMissingLabel prints the open issues, excluding pull requests, which
+// carry no label starting with prefix, oldest first.
+func ReportMissingLabel(p *Project, prefix string, now time.Time) {
+	var missing []*Issue
+	for _, i := range p.issues {
+		if i.ClosedAt != nil || i.PullRequestLinks != nil || i.CreatedAt == nil {
+			continue
+		}
+		if !i.hasLabelPrefix(prefix) {
+			missing = append(missing, i)
+		}
+	}
+	sort.Slice(missing, func(a, b int) bool {
+		return missing[a].CreatedAt.Before(*missing[b].CreatedAt)
+	})
+
+	fmt.Printf("open issues without a %s* label (%d)\n", prefix, len(missing))
+	for _, i := range missing {
+		fmt.Printf("  %s: %dd: %s\n", i.id(), int(now.Sub(*i.CreatedAt).Hours()/24), i.GetTitle())
+	}
+}
+
+// ReportMilestoneSizes prints the mean and maximum number of issues per
+// milestone, followed by the milestones with the most open issues. Closed
+// milestones are skipped unless includeClosed is set.
+func ReportMilestoneSizes(p *Project, includeClosed bool) {
+	type size struct {
+		m           *github.Milestone
+		open, total int
+	}
+	sizes := make(map[int]*size)
+	for _, m := range p.milestones {
+		if includeClosed || m.GetState() == "open" {
+			sizes[m.GetID()] = &size{m: m}
+		}
+	}
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil {
+			continue
+		}
+		s := sizes[i.Milestone.GetID()]
+		if s == nil {
+			continue
+		}
+		s.total++
+		if i.ClosedAt == nil {
+			s.open++
+		}
+	}
+
+	sorted := make([]*size, 0, len(sizes))
+	var total, max int
+	for _, s := range sizes {
+		sorted = append(sorted, s)
+		total += s.total
+		if s.total > max {
+			max = s.total
+		}
+	}
+	sort.Slice(sorted, func(a, b int) bool {
+		if sorted[a].open != sorted[b].open {
+			return sorted[a].open > sorted[b].open
+		}
+		return sorted[a].m.GetTitle() < sorted[b].m.GetTitle()
+	})
+
+	var mean float64
+	if len(sorted) > 0 {
+		mean = float64(total) / float64(len(sorted))
+	}
+	fmt.Printf("milestones (%d): mean=%0.1f max=%d issues\n", len(sorted), mean, max)
+	const top = 10
+	if len(sorted) > top {
+		sorted = sorted[:top]
+	}
+	for _, s := range sorted {
+		fmt.Printf("  %-30s %5d open %5d total\n", s.m.GetTitle(), s.open, s.total)
+	}
+}
+
+// milestonePeriods returns the periods during which the issue was in the
+// milestone with the given title, replaying milestoned and demilestoned
+// events in timestamp order. A period which has not ended runs until now.
+// An issue in the milestone without any such events, e.g. because its
+// timeline was not fetched, is treated as having been in it since it was
+// filed.
+func (i *Issue) milestonePeriods(title string, now time.Time) [][2]time.Time {
+	var events []*github.Timeline
+	for _, t := range i.Timeline {
+		switch t.GetEvent() {
+		case "milestoned", "demilestoned":
+			if t.Milestone.GetTitle() == title {
+				events = append(events, t)
+			}
+		}
+	}
+	sort.SliceStable(events, func(a, b int) bool {
+		return events[a].GetCreatedAt().Before(events[b].GetCreatedAt())
+	})
+
+	var periods [][2]time.Time
+	var start time.Time
+	for _, t := range events {
+		if t.GetEvent() == "milestoned" {
+			if start.IsZero() {
+				start = t.GetCreatedAt()
+			}
+			continue
+		}
+		if !start.IsZero() {
+			periods = append(periods, [2]time.Time{start, t.GetCreatedAt()})
+			start = time.Time{}
+		}
+	}
+	if len(events) == 0 && i.Milestone.GetTitle() == title && i.CreatedAt != nil {
+		start = *i.CreatedAt
+	}
+	if !start.IsZero() {
+		periods = append(periods, [2]time.Time{start, now})
+	}
+	return periods
+}
+
+// milestoneBurndown returns the number of open issues (excluding pull
+// requests) in the milestone at the end of every week from the milestone
+// being created until it was closed, or until now if it is still open.
+func (p *Project) milestoneBurndown(m *github.Milestone, now time.Time) []BacklogPoint {
+	first := m.GetCreatedAt()
+	last := now
+	if m.ClosedAt != nil && m.ClosedAt.Before(now) {
+		last = *m.ClosedAt
+	}
+	type change struct {
+		at time.Time
+		d  int
+	}
+	var changes []change
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil || i.CreatedAt == nil {
+			continue
+		}
+		for _, period := range i.milestonePeriods(m.GetTitle(), now) {
+			start, end := period[0], period[1]
+			if start.Before(*i.CreatedAt) {
+				start = *i.CreatedAt
+			}
+			if i.ClosedAt != nil && i.ClosedAt.Before(end) {
+				end = *i.ClosedAt
+			}
+			if !start.Before(end) {
+				continue
+			}
+			changes = append(changes, change{start, 1}, change{end, -1})
+			if first.IsZero() || start.Before(first) {
+				first = start
+			}
+		}
+	}
+	if first.IsZero() || first.After(last) {
+		return nil
+	}
+
+	delta := make(map[string]int)
+	for _, c := range changes {
+		if c.at.Before(first) {
+			c.at = first
+		}
+		if !c.at.Before(now) {
+			continue
+		}
+		delta[BucketKey(c.at, "week")] += c.d
+	}
+	var series []BacklogPoint
+	var open int
+	for _, k := range bucketRange(first, last, "week") {
+		open += delta[k]
+		series = append(series, BacklogPoint{Bucket: k, Open: open})
+	}
+	return series
+}
+
+// ReportMilestoneBurndown prints the weekly number of open issues in each
+// milestone. Closed milestones are skipped unless includeClosed is set.
+func ReportMilestoneBurndown(p *Project, includeClosed bool, now time.Time) {
+	var milestones []*github.Milestone
+	for _, m := range p.milestones {
+		if includeClosed || m.GetState() == "open" {
+			milestones = append(milestones, m)
+		}
+	}
+	sort.Slice(milestones, func(a, b int) bool {
+		return milestones[a].GetTitle() < milestones[b].GetTitle()
+	})
+
+	for _, m := range milestones {
+		fmt.Printf("milestone burndown: %s\n", m.GetTitle())
+		series := p.milestoneBurndown(m, now)
+		if len(series) == 0 {
+			fmt.Printf("  no data\n")
+			continue
+		}
+		fmt.Printf("  %-10s %7s\n", "week", "open")
+		for _, pt := range series {
+			fmt.Printf("  %-10s %7d\n", pt.Bucket, pt.Open)
+		}
+	}
+}
+
+// firstResponse returns the time of the earliest timeline event by someone
+// other than the issue's author, ignoring bots. The zero time is returned
+// if there is no such event.
+func (i *Issue) firstResponse() time.Time {
+	author := i.User.GetID()
+	var first time.Time
+	for _, t := range i.Timeline {
+		if t.Actor == nil || t.Actor.GetID() == author || IsBot(t.Actor) {
+			continue
+		}
+		if at := t.GetCreatedAt(); first.IsZero() || at.Before(first) {
+			first = at
+		}
+	}
+	return first
+}
+
+// FirstResponseHistogram records the time in hours from an issue being
+// filed to the first response from someone other than its author. Pull
+// requests, issues filed by maintainers and issues without a response are
+// skipped.
+func FirstResponseHistogram(p *Project) *hdrhistogram.Histogram {
+	var d []time.Duration
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil || i.CreatedAt == nil || p.isMaintainer(i.User) {
+			continue
+		}
+		if first := i.firstResponse(); !first.IsZero() {
+			d = append(d, first.Sub(*i.CreatedAt))
+		}
+	}
+	return hoursHistogram(d)
+}
+
+// maintainerReplies returns, for each maintainer login, the time from a
+// community comment to that maintainer's next comment on the same issue or
+// pull request. Only the earliest of several unanswered community comments
+// is counted, and comments by bots are ignored.
+func (p *Project) maintainerReplies() map[string][]time.Duration {
+	replies := make(map[string][]time.Duration)
+	for _, i := range p.issues {
+		var comments []*github.Timeline
+		for _, t := range i.Timeline {
+			if t.GetEvent() == "commented" && t.Actor != nil && !IsBot(t.Actor) {
+				comments = append(comments, t)
+			}
+		}
+		sort.SliceStable(comments, func(a, b int) bool {
+			return comments[a].GetCreatedAt().Before(comments[b].GetCreatedAt())
+		})
+
+		var pending time.Time
+		for _, t := range comments {
+			if !p.isMaintainer(t.Actor) {
+				if pending.IsZero() {
+					pending = t.GetCreatedAt()
+				}
+				continue
+			}
+			if !pending.IsZero() {
+				login := t.Actor.GetLogin()
+				replies[login] = append(replies[login], t.GetCreatedAt().Sub(pending))
+				pending = time.Time{}
+			}
+		}
+	}
+	return replies
+}
+
+// ReportMaintainerResponse prints, for each maintainer, the number of
+// community comments they replied to and the mean and median time taken to
+// reply.
+func ReportMaintainerResponse(p *Project) {
+	replies := p.maintainerReplies()
+	logins := make([]string, 0, len(replies))
+	for l := range replies {
+		logins = append(logins, l)
+	}
+	sort.Slice(logins, func(a, b int) bool {
+		if na, nb := len(replies[logins[a]]), len(replies[logins[b]]); na != nb {
+			return na > nb
+		}
+		return logins[a] < logins[b]
+	})
+
+	fmt.Printf("maintainer response to community comments\n")
+	if len(logins) == 0 {
+		fmt.Printf("  no data\n")
+		return
+	}
+	fmt.Printf("  %-20s %7s %8s %8s\n", "maintainer", "replies", "mean", "median")
+	for _, l := range logins {
+		h := hoursHistogram(replies[l])
+		fmt.Printf("  %-20s %7d %7.1fh %7dh\n", l, h.TotalCount(), h.Mean(), h.ValueAtQuantile(50))
+	}
+}
+
+// communityPRCounts returns the number of merged, closed but unmerged, and
+// open pull requests authored by users outside the project's team.
+func communityPRCounts(p *Project) (merged, unmerged, open int) {
+	for _, i := range p.issues {
+		if i.PullRequestLinks == nil || p.isMaintainer(i.User) {
+			continue
+		}
+		switch {
+		case i.ClosedAt == nil:
+			open++
+		case i.merged():
+			merged++
+		default:
+			unmerged++
+		}
+	}
+	return merged, unmerged, open
+}
+
+// ReportCommunityPRs prints the percentage of closed community pull
+// requests that were merged.
+func ReportCommunityPRs(p *Project) {
+	merged, unmerged, open := communityPRCounts(p)
+	var pct float64
+	if merged+unmerged > 0 {
+		pct = 100 * float64(merged) / float64(merged+unmerged)
+	}
+	fmt.Printf("community pull requests: merged=%d closed-unmerged=%d open=%d merged=%0.1f%%\n",
+		merged, unmerged, open, pct)
+}
+
+// contributorFunnel finds each community member's first issue (excluding
+// pull requests) and first merged pull request, and returns the number of
+// users who filed an issue along with, for those whose first merged pull
+// request came after their first issue, the time between the two.
+// Maintainers and bots are excluded.
+func (p *Project) contributorFunnel() (filers int, gaps []time.Duration) {
+	firstIssue := make(map[string]time.Time)
+	firstMerge := make(map[string]time.Time)
+	for _, i := range p.issues {
+		if i.User == nil || IsBot(i.User) || p.isMaintainer(i.User) {
+			continue
+		}
+		login := strings.ToLower(i.User.GetLogin())
+		if i.PullRequestLinks == nil {
+			if i.CreatedAt == nil {
+				continue
+			}
+			if t, ok := firstIssue[login]; !ok || i.CreatedAt.Before(t) {
+				firstIssue[login] = *i.CreatedAt
+			}
+			continue
+		}
+		merged := i.mergedAt()
+		if merged.IsZero() {
+			continue
+		}
+		if t, ok := firstMerge[login]; !ok || merged.Before(t) {
+			firstMerge[login] = merged
+		}
+	}
+	for login, issued := range firstIssue {
+		if merged, ok := firstMerge[login]; ok && merged.After(issued) {
+			gaps = append(gaps, merged.Sub(issued))
+		}
+	}
+	return len(firstIssue), gaps
+}
+
+// ReportContributorFunnel prints how many community issue filers went on to
+// have a pull request merged, and the time from their first issue to their
+// first merged pull request.
+func ReportContributorFunnel(p *Project) {
+	filers, gaps := p.contributorFunnel()
+	var pct float64
+	if filers > 0 {
+		pct = 100 * float64(len(gaps)) / float64(filers)
+	}
+	fmt.Printf("contributor funnel: issue-filers=%d went-on-to-merge=%d (%0.1f%%)\n",
+		filers, len(gaps), pct)
+	PrintDaysSummary("first issue to first merge", daysHistogram(gaps))
+}
+
+// ReportBuckets prints the number of issues opened and closed in each
+// bucket, in chronological order. An issue is counted as opened in the
+// bucket containing its creation and as closed in the bucket containing its
+// close, which may differ.
+func ReportBuckets(p *Project, mode string) {
+	type counts struct {
+		opened, closed int
+	}
+	buckets := make(map[string]*counts)
+	get := func(t time.Time) *counts {
+		k := BucketKey(t, mode)
+		c := buckets[k]
+		if c == nil {
+			c = &counts{}
+			buckets[k] = c
+		}
+		return c
+	}
+	for _, i := range p.issues {
+		if i.CreatedAt != nil {
+			get(*i.CreatedAt).opened++
+		}
+		if i.ClosedAt != nil {
+			get(*i.ClosedAt).closed++
+		}
+	}
+
+	keys := make([]string, 0, len(buckets))
+	for k := range buckets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("  %-10s %7s %7s\n", mode, "opened", "closed")
+	for _, k := range keys {
+		c := buckets[k]
+		fmt.Printf("  %-10s %7d %7d\n", k, c.opened, c.closed)
+	}
+}
+
+// positiveReactions returns the number of +1, heart and hooray reactions to
+// the issue, or 0 if reactions were not returned by GitHub.
+func (i *Issue) positiveReactions() int {
+	r := i.Reactions
+	if r == nil {
+		return 0
+	}
+	return r.GetPlusOne() + r.GetHeart() + r.GetHooray()
+}
+
+// mostReacted returns up to top open issues (excluding pull requests) with
+// positive reactions, most reacted first.
+func mostReacted(p *Project, top int) []*Issue {
+	var found []*Issue
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil || i.ClosedAt != nil {
+			continue
+		}
+		if i.positiveReactions() > 0 {
+			found = append(found, i)
+		}
+	}
+	sort.Slice(found, func(a, b int) bool {
+		ra, rb := found[a].positiveReactions(), found[b].positiveReactions()
+		if ra != rb {
+			return ra > rb
+		}
+		return found[a].GetNumber() < found[b].GetNumber()
+	})
+	if len(found) > top {
+		found = found[:top]
+	}
+	return found
+}
+
+// ReportReactions lists the top open issues (excluding pull requests) by
+// number of positive reactions, i.e. the most requested issues.
+func ReportReactions(p *Project, top int) {
+	found := mostReacted(p, top)
+	fmt.Printf("most reacted open issues (%d)\n", len(found))
+	for _, i := range found {
+		fmt.Printf("  %s: %d reactions: %s\n", i.id(), i.positiveReactions(), i.GetTitle())
+	}
+}
+
+// firstReview returns the time of the earliest review of the pull request by
+// someone other than its author, or the zero time if there is none. Both
+// reviewed events in the timeline and the cached reviews are consulted, as
+// GitHub reports the reviewer and time of reviewed timeline events in fields
+// that are not always decoded.
+func (i *Issue) firstReview() time.Time {
+	author := i.User.GetID()
+	var first time.Time
+	consider := func(u *github.User, t time.Time) {
+		if u == nil || u.GetID() == author || t.IsZero() {
+			return
+		}
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+	}
+	for _, t := range i.Timeline {
+		if t.GetEvent() == "reviewed" {
+			consider(t.Actor, t.GetCreatedAt())
+		}
+	}
+	for _, r := range i.Reviews {
+		consider(r.User, r.GetSubmittedAt())
+	}
+	return first
+}
+
+// ReviewLatencyHistogram returns a histogram of the hours from a pull
+// request being opened to its first review. Pull requests without a review
+// from someone other than their author are skipped.
+func ReviewLatencyHistogram(p *Project) *hdrhistogram.Histogram {
+	var d []time.Duration
+	for _, i := range p.issues {
+		if i.PullRequestLinks == nil || i.CreatedAt == nil {
+			continue
+		}
+		if first := i.firstReview(); !first.IsZero() {
+			d = append(d, first.Sub(*i.CreatedAt))
+		}
+	}
+	return hoursHistogram(d)
+}
+
+// BacklogPoint is the number of open issues at the end of a bucket.
+type BacklogPoint struct {
+	Bucket string
+	Open   int
+}
+
+// backlogSeries returns the number of open issues (excluding pull requests)
+// at the end of every bucket from the first issue being filed to the last
+// issue being filed or closed. Unlike ReportBuckets the counts are
+// cumulative, so an issue contributes to every bucket from the one it was
+// created in up to, but not including, the one it was closed in.
+func (p *Project) backlogSeries(bucket string) []BacklogPoint {
+	delta := make(map[string]int)
+	var first, last time.Time
+	see := func(t time.Time, d int) {
+		delta[BucketKey(t, bucket)] += d
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil || i.CreatedAt == nil {
+			continue
+		}
+		see(*i.CreatedAt, 1)
+		if i.ClosedAt != nil {
+			see(*i.ClosedAt, -1)
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+
+	var series []BacklogPoint
+	var open int
+	for _, k := range bucketRange(first, last, bucket) {
+		open += delta[k]
+		series = append(series, BacklogPoint{Bucket: k, Open: open})
+	}
+	return series
+}
+
+// ReportBacklog prints the number of open issues at the end of each bucket.
+func ReportBacklog(p *Project, bucket string) {
+	fmt.Printf("  %-10s %7s\n", bucket, "open")
+	for _, pt := range p.backlogSeries(bucket) {
+		fmt.Printf("  %-10s %7d\n", pt.Bucket, pt.Open)
+	}
+}
+
+// lastActivity returns the time of the most recent timeline event on the
+// issue, or its creation time if the timeline is empty.
+func (i *Issue) lastActivity() time.Time {
+	last := i.GetCreatedAt()
+	for _, t := range i.Timeline {
+		if c := t.GetCreatedAt(); c.After(last) {
+			last = c
+		}
+	}
+	return last
+}
+
+// staleItems returns the open issues and pull requests without any timeline
+// activity in the given number of days, oldest first.
+func staleItems(p *Project, days int, now time.Time) (issues, prs []*Issue) {
+	cutoff := now.AddDate(0, 0, -days)
+	for _, i := range p.issues {
+		if i.ClosedAt != nil || !i.lastActivity().Before(cutoff) {
+			continue
+		}
+		if i.PullRequestLinks != nil {
+			prs = append(prs, i)
+		} else {
+			issues = append(issues, i)
+		}
+	}
+	for _, stale := range [][]*Issue{issues, prs} {
+		sort.Slice(stale, func(a, b int) bool {
+			return stale[a].lastActivity().Before(stale[b].lastActivity())
+		})
+	}
+	return issues, prs
+}
+
+// ReportStale lists open issues and pull requests without any timeline
+// activity in the given number of days, oldest first.
+func ReportStale(p *Project, days int, now time.Time) {
+	issues, prs := staleItems(p, days, now)
+	list := func(name string, stale []*Issue) {
+		fmt.Printf("stale %s (%d, no activity in %dd)\n", name, len(stale), days)
+		for _, i := range stale {
+			last := i.lastActivity()
+			fmt.Printf("  %s: %dd, last %s: %s\n", i.id(),
+				int(now.Sub(last).Hours()/24), last.Format("2006-01-02"), i.GetTitle())
+		}
+	}
+	list("issues", issues)
+	list("pull requests", prs)
+}
+
+// participants returns the number of distinct users who authored the issue
+// or appear as the actor of a timeline event.
+func (i *Issue) participants() int {
+	seen := make(map[int]bool)
+	if id := i.User.GetID(); id != 0 {
+		seen[id] = true
+	}
+	for _, t := range i.Timeline {
+		if id := t.Actor.GetID(); id != 0 {
+			seen[id] = true
+		}
+	}
+	return len(seen)
+}
+
+// EngagementHistogram returns histograms of the number of comments on and
+// the number of participants in each issue and pull request.
+func (p *Project) EngagementHistogram() (comments, participants *hdrhistogram.Histogram) {
+	comments = hdrhistogram.New(1, 100000, 2)
+	participants = hdrhistogram.New(1, 100000, 2)
+	for _, i := range p.issues {
+		comments.RecordValue(int64(i.GetComments()))
+		participants.RecordValue(int64(i.participants()))
+	}
+	return comments, participants
+}
+
+// labelCooccurrence returns the number of issues on which each pair of
+// labels appears together. Pairs are ordered by name so that each is only
+// counted once.
+func (p *Project) labelCooccurrence() map[[2]string]int {
+	counts := make(map[[2]string]int)
+	for _, i := range p.issues {
+		names := make([]string, 0, len(i.Labels))
+		for _, l := range i.Labels {
+			names = append(names, l.GetName())
+		}
+		sort.Strings(names)
+		for a := 0; a < len(names); a++ {
+			for b := a + 1; b < len(names); b++ {
+				if names[a] != names[b] {
+					counts[[2]string{names[a], names[b]}]++
+				}
+			}
+		}
+	}
+	return counts
+}
+
+// ReportLabelCooccurrence prints the top label pairs by the number of
+// issues carrying both.
+func ReportLabelCooccurrence(p *Project, top int) {
+	counts := p.labelCooccurrence()
+	pairs := make([][2]string, 0, len(counts))
+	for pair := range counts {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		if ca, cb := counts[pairs[a]], counts[pairs[b]]; ca != cb {
+			return ca > cb
+		}
+		if pairs[a][0] != pairs[b][0] {
+			return pairs[a][0] < pairs[b][0]
+		}
+		return pairs[a][1] < pairs[b][1]
+	})
+	if len(pairs) > top {
+		pairs = pairs[:top]
+	}
+
+	fmt.Printf("label pairs (top %d)\n", len(pairs))
+	for _, pair := range pairs {
+		fmt.Printf("  %5d  %s + %s\n", counts[pair], pair[0], pair[1])
+	}
+}
+
+// labelChurn holds the number of times a label was applied to and removed
+// from issues.
+type labelChurn struct {
+	name             string
+	applies, removes int
+}
+
+// ratio returns the label's removals as a fraction of its applications.
+func (c *labelChurn) ratio() float64 {
+	return ratio(c.removes, c.applies)
+}
+
+// labelChurn counts the labeled and unlabeled timeline events for each
+// label across all issues and pull requests.
+func (p *Project) labelChurn() []*labelChurn {
+	counts := make(map[string]*labelChurn)
+	for _, i := range p.issues {
+		for _, t := range i.Timeline {
+			event := t.GetEvent()
+			if event != "labeled" && event != "unlabeled" {
+				continue
+			}
+			name := t.Label.GetName()
+			c := counts[name]
+			if c == nil {
+				c = &labelChurn{name: name}
+				counts[name] = c
+			}
+			if event == "labeled" {
+				c.applies++
+			} else {
+				c.removes++
+			}
+		}
+	}
+	churn := make([]*labelChurn, 0, len(counts))
+	for _, c := range counts {
+		churn = append(churn, c)
+	}
+	return churn
+}
+
+// ReportLabelChurn prints the top labels by the ratio of removals to
+// applications, which points at labels triagers disagree about or misuse.
+func ReportLabelChurn(p *Project, top int) {
+	churn := p.labelChurn()
+	sort.Slice(churn, func(a, b int) bool {
+		if ra, rb := churn[a].ratio(), churn[b].ratio(); ra != rb {
+			return ra > rb
+		}
+		if churn[a].applies != churn[b].applies {
+			return churn[a].applies > churn[b].applies
+		}
+		return churn[a].name < churn[b].name
+	})
+	if len(churn) > top {
+		churn = churn[:top]
+	}
+
+	fmt.Printf("label churn (top %d)\n", len(churn))
+	fmt.Printf("  %7s %7s %6s  %s\n", "applies", "removes", "churn", "label")
+	for _, c := range churn {
+		fmt.Printf("  %7d %7d %5.1f%%  %s\n", c.applies, c.removes, 100*c.ratio(), c.name)
+	}
+}
+
+// TriageHistogram returns a histogram of the hours from an issue being
+// filed to the first label being applied to it. Pull requests and issues
+// which were never labeled are skipped.
+func TriageHistogram(p *Project) *hdrhistogram.Histogram {
+	var d []time.Duration
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil || i.CreatedAt == nil {
+			continue
+		}
+		if labeled := i.firstLabeled(); !labeled.IsZero() {
+			d = append(d, labeled.Sub(*i.CreatedAt))
+		}
+	}
+	return hoursHistogram(d)
+}
+
+// NeverLabeled returns true for issues, other than pull requests, which
+// have never had a label applied.
+func NeverLabeled(i *Issue) bool {
+	return i.PullRequestLinks == nil && i.firstLabeled().IsZero()
+}
+
+// sparkBlocks are the characters used by sparkline, from lowest to highest.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline renders values as a line of block characters scaled so that the
+// largest value is a full block.
+func sparkline(values []int) string {
+	var max int
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	var b strings.Builder
+	for _, v := range values {
+		j := 0
+		if max > 0 {
+			j = v * (len(sparkBlocks) - 1) / max
+		}
+		b.WriteRune(sparkBlocks[j])
+	}
+	return b.String()
+}
+
+// ReportMergeSparkline prints the number of pull requests merged each ISO
+// week as a sparkline, followed by the range of weeks it covers.
+func ReportMergeSparkline(p *Project) {
+	counts := make(map[string]int)
+	var first, last time.Time
+	for _, i := range p.issues {
+		t := i.mergedAt()
+		if t.IsZero() {
+			continue
+		}
+		counts[BucketKey(t, "week")]++
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	if first.IsZero() {
+		fmt.Printf("merged prs per week: no data\n")
+		return
+	}
+	weeks := bucketRange(first, last, "week")
+	values := make([]int, len(weeks))
+	var max int
+	for j, k := range weeks {
+		values[j] = counts[k]
+		if values[j] > max {
+			max = values[j]
+		}
+	}
+	fmt.Printf("merged prs per week (max %d)\n", max)
+	fmt.Printf("  %s\n", sparkline(values))
+	fmt.Printf("  %s to %s\n", weeks[0], weeks[len(weeks)-1])
+}
+
+// reviewed returns true if anyone other than the author reviewed the pull
+// request.
+func (i *Issue) reviewed() bool {
+	return !i.firstReview().IsZero()
+}
+
+// closeReason classifies who closed an unmerged pull request: a bot (such
+// as a stale bot), the author abandoning it, or someone else, typically a
+// maintainer rejecting it.
+func (i *Issue) closeReason() string {
+	c := i.closer()
+	switch {
+	case c == nil:
+		return "unknown"
+	case IsBot(c):
+		return "bot"
+	case c.GetID() == i.User.GetID():
+		return "author"
+	default:
+		return "other"
+	}
+}
+
+// ReportAbandonedPRs prints the number of pull requests closed without being
+// merged by who closed them, followed by up to top of them ordered by age at
+// close, oldest first, noting whether each was ever reviewed.
+func ReportAbandonedPRs(p *Project, top int) {
+	var closed []*Issue
+	reasons := make(map[string]int)
+	for _, i := range p.issues {
+		if i.PullRequestLinks == nil || i.ClosedAt == nil || i.CreatedAt == nil || i.merged() {
+			continue
+		}
+		closed = append(closed, i)
+		reasons[i.closeReason()]++
+	}
+	age := func(i *Issue) time.Duration {
+		return i.ClosedAt.Sub(*i.CreatedAt)
+	}
+	sort.Slice(closed, func(a, b int) bool {
+		if da, db := age(closed[a]), age(closed[b]); da != db {
+			return da > db
+		}
+		return closed[a].GetNumber() < closed[b].GetNumber()
+	})
+
+	fmt.Printf("closed unmerged pull requests (%d)\n", len(closed))
+	for _, r := range []string{"author", "other", "bot", "unknown"} {
+		if n := reasons[r]; n > 0 {
+			fmt.Printf("  closed by %s: %d (%0.1f%%)\n", r, n, 100*float64(n)/float64(len(closed)))
+		}
+	}
+	if len(closed) > top {
+		closed = closed[:top]
+	}
+	for _, i := range closed {
+		review := "unreviewed"
+		if i.reviewed() {
+			review = "reviewed"
+		}
+		fmt.Printf("  %s: %s, %0.1fd, %s, closed by %s: %s\n", i.id(), i.User.GetLogin(),
+			age(i).Hours()/24, review, i.closeReason(), i.GetTitle())
+	}
+}
+
+// sizeClasses are the pull request size classes reported by ReportPRSizes,
+// each holding pull requests changing fewer than max lines.
+var sizeClasses = []struct {
+	name string
+	max  int
+}{
+	{"XS", 10},
+	{"S", 50},
+	{"M", 250},
+	{"L", 1000},
+	{"XL", math.MaxInt32},
+}
+
+// changedLines returns the number of lines added and deleted by the pull
+// request, from the sizes fetched with -fetch-sizes or else by summing the
+// files fetched with -fetch-files. It returns false if neither is cached.
+func (i *Issue) changedLines() (int, bool) {
+	if i.Additions != nil && i.Deletions != nil {
+		return *i.Additions + *i.Deletions, true
+	}
+	if i.Files == nil {
+		return 0, false
+	}
+	var n int
+	for _, f := range i.Files {
+		n += f.GetAdditions() + f.GetDeletions()
+	}
+	return n, true
+}
+
+// ReportPRSizes prints, for each size class of merged pull requests, the
+// number merged and the mean and median time from creation to merge, to
+// show whether larger pull requests take longer to land.
+func ReportPRSizes(p *Project) {
+	times := make([][]time.Duration, len(sizeClasses))
+	var unknown int
+	for _, i := range p.issues {
+		if i.PullRequestLinks == nil || i.CreatedAt == nil {
+			continue
+		}
+		merged := i.mergedAt()
+		if merged.IsZero() {
+			continue
+		}
+		lines, ok := i.changedLines()
+		if !ok {
+			unknown++
+			continue
+		}
+		for j, c := range sizeClasses {
+			if lines < c.max {
+				times[j] = append(times[j], merged.Sub(*i.CreatedAt))
+				break
+			}
+		}
+	}
+
+	fmt.Printf("merge time by pr size\n")
+	fmt.Printf("  %-4s %-10s %6s %8s %8s\n", "size", "lines", "merged", "mean", "median")
+	lower := 0
+	for j, c := range sizeClasses {
+		bounds := fmt.Sprintf("%d-%d", lower, c.max-1)
+		if c.max == math.MaxInt32 {
+			bounds = fmt.Sprintf("%d+", lower)
+		}
+		lower = c.max
+		h := hoursHistogram(times[j])
+		if h.TotalCount() == 0 {
+			fmt.Printf("  %-4s %-10s %6d %8s %8s\n", c.name, bounds, 0, "-", "-")
+			continue
+		}
+		fmt.Printf("  %-4s %-10s %6d %7.1fd %7.1fd\n", c.name, bounds, h.TotalCount(),
+			h.Mean()/24, float64(h.ValueAtQuantile(50))/24)
+	}
+	if unknown > 0 {
+		fmt.Printf("  %d merged pull requests without size data; refresh with -fetch-sizes\n", unknown)
+	}
+}
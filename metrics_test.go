@@ -0,0 +1,36 @@
+package roachpulse
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestHasLabels(t *testing.T) {
+	i := &Issue{Issue: github.Issue{Labels: []github.Label{
+		{Name: github.String("C-bug")},
+		{Name: github.String("A-sql")},
+	}}}
+	testCases := []struct {
+		names    []string
+		all, any bool
+	}{
+		{nil, true, false},
+		{[]string{"C-bug"}, true, true},
+		{[]string{"C-feature"}, false, false},
+		{[]string{"C-bug", "A-sql"}, true, true},
+		{[]string{"C-bug", "C-feature"}, false, true},
+		{[]string{"C-feature", "A-kv"}, false, false},
+	}
+	for _, c := range testCases {
+		t.Run(fmt.Sprint(c.names), func(t *testing.T) {
+			if got := i.HasLabels(c.names, false); got != c.all {
+				t.Errorf("all: expected %t, got %t", c.all, got)
+			}
+			if got := i.HasLabels(c.names, true); got != c.any {
+				t.Errorf("any: expected %t, got %t", c.any, got)
+			}
+		})
+	}
+}
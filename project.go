@@ -0,0 +1,995 @@
+package roachpulse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// Progress receives the progress messages printed while loading and
+// refreshing. It is stderr so that stdout only carries the reports, and is
+// discarded with -q.
+var Progress io.Writer = os.Stderr
+
+func saveJSON(path string, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Write to a temporary file and rename it into place so that an
+	// interrupted write never leaves a truncated file behind.
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0666); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// loadJSON decodes the JSON in path into v, returning false if path does
+// not exist or could not be decoded. A corrupt file is reported and skipped
+// rather than aborting, as it is refetched by the next refresh.
+func loadJSON(path string, v interface{}) bool {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false
+		}
+		log.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		log.Printf("skipping %s: %s", path, err)
+		return false
+	}
+	return true
+}
+
+// WriteFile creates path and passes it to fn, closing the file afterwards.
+// The file is written under a temporary name and renamed into place once fn
+// succeeds, so path is never left truncated.
+func WriteFile(path string, fn func(w io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// NewClient returns a GitHub client which issues requests using httpClient.
+// If baseURL is non-empty it replaces the default GitHub API endpoint, which
+// allows pointing the client at GitHub Enterprise or a fake server.
+func NewClient(httpClient *http.Client, baseURL string) (*github.Client, error) {
+	client := github.NewClient(httpClient)
+	if baseURL != "" {
+		if !strings.HasSuffix(baseURL, "/") {
+			baseURL += "/"
+		}
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return nil, err
+		}
+		client.BaseURL = u
+	}
+	return client, nil
+}
+
+// Issue ...
+type Issue struct {
+	github.Issue
+	Timeline []*github.Timeline
+	// TimelineNextPage is the next page of the timeline to fetch when a
+	// refresh was interrupted part way through it, and 0 otherwise.
+	TimelineNextPage int `json:",omitempty"`
+	Commits          []*github.RepositoryCommit
+	Reviews          []*github.PullRequestReview
+	// Files is only fetched when refreshing with -fetch-files.
+	Files []*github.CommitFile
+	// Additions and Deletions count the lines changed by a pull request.
+	// They are only fetched when refreshing with -fetch-sizes.
+	Additions *int `json:",omitempty"`
+	Deletions *int `json:",omitempty"`
+	// Source is the owner/repo the issue belongs to when it is part of a
+	// merged project, and empty otherwise.
+	Source string `json:"-"`
+}
+
+// schemaVersion is the version of the cache format written by Save. It must
+// be incremented whenever the on-disk format of Project or Issue changes,
+// raising minSchemaVersion to match unless Load can still make sense of
+// older caches.
+//
+// Version 2 added Issue.TimelineNextPage. Version 1 caches never hold a
+// partial timeline, so the field's absence correctly reads as complete.
+const schemaVersion = 2
+
+// minSchemaVersion is the oldest cache format Load accepts. Caches written
+// before the version was recorded have no version at all, and as nothing
+// is known about their format they are rejected rather than read.
+const minSchemaVersion = 1
+
+// Project ...
+type Project struct {
+	Owner         string
+	Repo          string
+	RefreshedAt   time.Time
+	SchemaVersion int
+
+	// Store selects the cache storage backend, StoreDir or StoreNDJSON.
+	Store string `json:"-"`
+	// Workers is the number of issues whose details are fetched
+	// concurrently by Refresh.
+	Workers int `json:"-"`
+	// FetchFiles makes Refresh fetch the files changed by pull requests.
+	FetchFiles bool `json:"-"`
+	// FetchSizes makes Refresh fetch the number of lines changed by pull
+	// requests, at the cost of a request per pull request.
+	FetchSizes bool `json:"-"`
+	// DryRun makes Refresh and RefreshIssue only report the work they
+	// would do.
+	DryRun bool `json:"-"`
+
+	cacheDir   string
+	sources    []*Project
+	issues     map[int]*Issue
+	team       map[string]bool
+	users      map[int]*github.User
+	milestones map[int]*github.Milestone
+	repos      map[int]*github.Repository
+}
+
+// NewProject returns an empty project for the GitHub repository owner/repo,
+// cached in cacheDir. Call Load to read the cache.
+func NewProject(owner, repo, cacheDir string) *Project {
+	return &Project{
+		Owner:      owner,
+		Repo:       repo,
+		Store:      StoreDir,
+		Workers:    8,
+		cacheDir:   cacheDir,
+		issues:     make(map[int]*Issue),
+		team:       make(map[string]bool),
+		users:      make(map[int]*github.User),
+		milestones: make(map[int]*github.Milestone),
+		repos:      make(map[int]*github.Repository),
+	}
+}
+
+// CacheDir returns the directory holding the project's cache.
+func (p *Project) CacheDir() string {
+	return p.cacheDir
+}
+
+const timeFormat = "2006-01-02 15:04:05"
+
+// perPage is the page size requested from the GitHub API.
+const perPage = 100
+
+// Refresh fetches the issues updated since the project was last refreshed,
+// along with their details, and saves them to the cache. An interrupted
+// refresh leaves the cache consistent, so it can be resumed later.
+func (p *Project) Refresh(ctx context.Context, client *github.Client) error {
+	if p.RefreshedAt != (time.Time{}) {
+		fmt.Fprintf(Progress, "refeshing issues since @ %s\n", p.RefreshedAt.Format(timeFormat))
+	} else {
+		fmt.Fprintf(Progress, "loading issues\n")
+	}
+
+	start := time.Now()
+
+	// Fetch the issue list on a separate goroutine so that the next page is
+	// requested while the current one is being processed. Pagination is
+	// inherently sequential, so a single page of read-ahead is all we get,
+	// except on a cold load where the number of pages is known up front.
+	pages := make(chan []*github.Issue, 1)
+	list := func(page int) ([]*github.Issue, *github.Response, error) {
+		var issues []*github.Issue
+		var resp *github.Response
+		err := withRetry(ctx, func() (*github.Response, error) {
+			var err error
+			issues, resp, err = client.Issues.ListByRepo(
+				ctx, p.Owner, p.Repo,
+				&github.IssueListByRepoOptions{
+					State:     "all",
+					Direction: "asc",
+					Since:     p.RefreshedAt,
+					ListOptions: github.ListOptions{
+						Page:    page,
+						PerPage: perPage,
+					},
+				},
+			)
+			return resp, err
+		})
+		return issues, resp, err
+	}
+	var listErr error
+	go func() {
+		defer close(pages)
+		page := 1
+		if p.RefreshedAt.IsZero() {
+			var err error
+			if page, err = p.listPagesConcurrently(list, pages); err != nil {
+				listErr = err
+				return
+			}
+		}
+		for page != 0 {
+			issues, resp, err := list(page)
+			if err != nil {
+				listErr = err
+				return
+			}
+			pages <- issues
+			page = resp.NextPage
+		}
+	}()
+
+	// With -dry-run the listed issues are only counted; nothing is changed.
+	var listRequests int
+	updated := make(map[int]*github.Issue)
+	for issues := range pages {
+		listRequests++
+		if n := len(issues); n > 0 {
+			fmt.Fprintf(Progress, "  %3d: %d-%d\n", n, issues[0].GetNumber(), issues[n-1].GetNumber())
+		}
+		for _, issue := range issues {
+			if issue.Number == nil {
+				continue
+			}
+			if p.DryRun {
+				updated[issue.GetNumber()] = issue
+				continue
+			}
+			i := p.issues[*issue.Number]
+			if i == nil {
+				i = &Issue{}
+				p.issues[*issue.Number] = i
+			}
+			// An issue unchanged since it was cached is only listed again
+			// because an interrupted refresh left RefreshedAt behind. Keep
+			// the details it fetched so that fetchDetails resumes them.
+			unchanged := i.UpdatedAt != nil && issue.UpdatedAt != nil && i.UpdatedAt.Equal(*issue.UpdatedAt)
+			i.Issue = *issue
+			if unchanged {
+				continue
+			}
+			i.Timeline = nil
+			i.TimelineNextPage = 0
+			i.Commits = nil
+			i.Reviews = nil
+			i.Files = nil
+			i.Additions = nil
+			i.Deletions = nil
+		}
+	}
+	if listErr != nil {
+		if ctx.Err() != nil {
+			return p.interrupted(ctx)
+		}
+		log.Fatal(listErr)
+	}
+
+	fmt.Fprintf(Progress, "  done\n")
+	if p.DryRun {
+		p.reportDryRun(updated, listRequests)
+		return nil
+	}
+	fmt.Fprintf(Progress, "refreshing timelines\n")
+
+	// Fetch the details of each issue on a pool of workers. Each issue is
+	// only touched by the worker fetching it until it is handed back, and
+	// interning and saving fetched issues happen on this goroutine as the
+	// intern maps are not safe for concurrent use. Workers only save the
+	// partial timelines of their own issues.
+	type result struct {
+		i       *Issue
+		changed bool
+		err     error
+	}
+	jobs := make(chan *Issue)
+	results := make(chan result)
+	n := p.Workers
+	if n < 1 {
+		n = 1
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				changed, err := p.fetchDetails(ctx, client, i)
+				results <- result{i: i, changed: changed, err: err}
+			}
+		}()
+	}
+	go func() {
+		sorted := p.sortedIssues()
+	feed:
+		for j := len(sorted) - 1; j >= 0; j-- {
+			select {
+			case jobs <- p.issues[sorted[j]]:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	for r := range results {
+		if r.err != nil {
+			// An issue whose details were only partially fetched is not
+			// saved beyond the timeline pages its worker already wrote, so
+			// the next refresh fetches the rest.
+			if ctx.Err() != nil {
+				continue
+			}
+			log.Fatal(r.err)
+		}
+		if i := r.i; r.changed {
+			fmt.Fprintf(Progress, "  %d (%d commits, %d reviews, %d events)\n",
+				*i.Number, len(i.Commits), len(i.Reviews), len(i.Timeline))
+			p.internIssue(i)
+			p.saveIssue(i)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return p.interrupted(ctx)
+	}
+
+	// Only advance RefreshedAt once every updated issue has been saved with
+	// its details. Issues updated since the previous refresh are reset in
+	// memory but not on disk, so if we stopped early the next run has to list
+	// them again rather than trusting their stale cached details.
+	p.RefreshedAt = start
+	p.Save()
+
+	fmt.Fprintf(Progress, "  done\n")
+	return nil
+}
+
+// RefreshIssue fetches a single issue and its details, replacing any cached
+// copy. RefreshedAt is left alone as other issues may be out of date. With
+// DryRun set, only the issue itself is fetched and nothing is saved.
+func (p *Project) RefreshIssue(ctx context.Context, client *github.Client, num int) error {
+	fmt.Fprintf(Progress, "refreshing issue %d\n", num)
+	var issue *github.Issue
+	err := withRetry(ctx, func() (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		issue, resp, err = client.Issues.Get(ctx, p.Owner, p.Repo, num)
+		return resp, err
+	})
+	if err != nil {
+		return err
+	}
+	i := &Issue{Issue: *issue}
+	if p.DryRun {
+		fmt.Printf("dry run: issue %d needs details: at least %d requests\n",
+			num, i.pendingFetches(p.FetchFiles, p.FetchSizes))
+		return nil
+	}
+	if _, err := p.fetchDetails(ctx, client, i); err != nil {
+		return err
+	}
+	fmt.Fprintf(Progress, "  %d (%d commits, %d reviews, %d events)\n",
+		num, len(i.Commits), len(i.Reviews), len(i.Timeline))
+	p.internIssue(i)
+	p.issues[num] = i
+	p.saveIssue(i)
+	p.Save()
+	return nil
+}
+
+// listPagesConcurrently fetches the first page of issues to learn the
+// number of pages, then fetches the remaining pages on p.Workers goroutines,
+// sending each to pages as it arrives. It returns the page to continue from
+// serially, or 0 if every page was fetched. This is only possible on a cold
+// load, as the page count of an incremental listing shifts while it is
+// read.
+func (p *Project) listPagesConcurrently(
+	list func(page int) ([]*github.Issue, *github.Response, error), pages chan<- []*github.Issue,
+) (int, error) {
+	issues, resp, err := list(1)
+	if err != nil {
+		return 0, err
+	}
+	pages <- issues
+	if resp.LastPage <= 1 {
+		// Either this was the only page or GitHub did not report the last
+		// page, in which case the remaining pages are fetched serially.
+		return resp.NextPage, nil
+	}
+
+	last := resp.LastPage
+	jobs := make(chan int)
+	var mu sync.Mutex
+	var firstErr error
+	var next int
+	n := p.Workers
+	if n < 1 {
+		n = 1
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range jobs {
+				issues, resp, err := list(page)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					continue
+				}
+				pages <- issues
+				if page == last {
+					// If GitHub capped the reported page count there are
+					// more pages after the last one.
+					mu.Lock()
+					next = resp.NextPage
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for page := 2; page <= last; page++ {
+		jobs <- page
+	}
+	close(jobs)
+	wg.Wait()
+	return next, firstErr
+}
+
+// pendingFetches returns the number of requests needed to fetch the missing
+// details of the issue, assuming each fits in a single page.
+func (i *Issue) pendingFetches(fetchFiles, fetchSizes bool) int {
+	var n int
+	if i.PullRequestLinks != nil {
+		if i.Commits == nil {
+			n++
+		}
+		if i.Reviews == nil {
+			n++
+		}
+		if fetchFiles && i.Files == nil {
+			n++
+		}
+		if fetchSizes && i.Additions == nil {
+			n++
+		}
+	}
+	if !i.timelineComplete() {
+		n++
+	}
+	return n
+}
+
+// reportDryRun prints the work a refresh would do given the issues listed as
+// updated since the last refresh. Updated issues have all of their details
+// refetched, and other cached issues only those which are missing.
+func (p *Project) reportDryRun(updated map[int]*github.Issue, listRequests int) {
+	var issues, requests int
+	for num, i := range p.issues {
+		if updated[num] != nil {
+			continue
+		}
+		if n := i.pendingFetches(p.FetchFiles, p.FetchSizes); n > 0 {
+			issues++
+			requests += n
+		}
+	}
+	for _, issue := range updated {
+		i := &Issue{Issue: *issue}
+		issues++
+		requests += i.pendingFetches(p.FetchFiles, p.FetchSizes)
+	}
+	fmt.Printf("dry run: %d issues updated (%d list requests)\n", len(updated), listRequests)
+	fmt.Printf("  %d issues need details: at least %d requests\n", issues, requests)
+}
+
+// interrupted saves the issues fetched by a refresh that was cancelled
+// before completing and returns the reason it was cancelled. RefreshedAt is
+// not advanced, so the next refresh picks up where this one left off.
+func (p *Project) interrupted(ctx context.Context) error {
+	fmt.Fprintf(Progress, "  interrupted; saving fetched issues\n")
+	p.Save()
+	return ctx.Err()
+}
+
+// Reconcile removes cached issues which no longer exist in the repository,
+// typically because they were deleted or transferred to another repository.
+// Incremental refreshes never see such issues, so this lists every issue
+// number currently on GitHub and diffs it against the cache.
+func (p *Project) Reconcile(ctx context.Context, client *github.Client) error {
+	fmt.Fprintf(Progress, "reconciling issues\n")
+
+	present := make(map[int]bool)
+	for page := 1; ; {
+		var issues []*github.Issue
+		var resp *github.Response
+		err := withRetry(ctx, func() (*github.Response, error) {
+			var err error
+			issues, resp, err = client.Issues.ListByRepo(
+				ctx, p.Owner, p.Repo,
+				&github.IssueListByRepoOptions{
+					State:     "all",
+					Direction: "asc",
+					ListOptions: github.ListOptions{
+						Page:    page,
+						PerPage: perPage,
+					},
+				},
+			)
+			return resp, err
+		})
+		if err != nil {
+			return err
+		}
+		for _, issue := range issues {
+			present[issue.GetNumber()] = true
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		page = resp.NextPage
+	}
+	if len(present) == 0 && len(p.issues) > 0 {
+		log.Fatalf("reconcile: GitHub listed no issues for %s/%s; refusing to purge the cache",
+			p.Owner, p.Repo)
+	}
+
+	var removed int
+	for _, num := range p.sortedIssues() {
+		if present[num] || num <= 0 {
+			continue
+		}
+		p.removeIssue(num)
+		removed++
+		fmt.Fprintf(Progress, "  removed %d\n", num)
+	}
+	if removed > 0 {
+		p.Save()
+	}
+	fmt.Fprintf(Progress, "  done (%d removed)\n", removed)
+	return nil
+}
+
+// fetchDetails fetches the commits, reviews, files and timeline of the
+// issue which have not already been fetched, returning true if anything
+// was fetched.
+func (p *Project) fetchDetails(ctx context.Context, client *github.Client, i *Issue) (bool, error) {
+	num := i.GetNumber()
+	changed := false
+	if i.PullRequestLinks != nil && i.Commits == nil {
+		for page := 1; ; {
+			var commits []*github.RepositoryCommit
+			var resp *github.Response
+			err := withRetry(ctx, func() (*github.Response, error) {
+				var err error
+				commits, resp, err = client.PullRequests.ListCommits(
+					ctx, p.Owner, p.Repo, num,
+					&github.ListOptions{
+						Page:    page,
+						PerPage: perPage,
+					},
+				)
+				return resp, err
+			})
+			if err != nil {
+				return changed, err
+			}
+			i.Commits = append(i.Commits, commits...)
+			changed = true
+			if resp.NextPage == 0 {
+				break
+			}
+			page = resp.NextPage
+		}
+	}
+	if i.PullRequestLinks != nil && i.Reviews == nil {
+		// Use an empty (rather than nil) slice so that pull requests
+		// without reviews are not refetched on every refresh.
+		i.Reviews = []*github.PullRequestReview{}
+		for page := 1; ; {
+			var reviews []*github.PullRequestReview
+			var resp *github.Response
+			err := withRetry(ctx, func() (*github.Response, error) {
+				var err error
+				reviews, resp, err = client.PullRequests.ListReviews(
+					ctx, p.Owner, p.Repo, num,
+					&github.ListOptions{
+						Page:    page,
+						PerPage: perPage,
+					},
+				)
+				return resp, err
+			})
+			if err != nil {
+				return changed, err
+			}
+			i.Reviews = append(i.Reviews, reviews...)
+			changed = true
+			if resp.NextPage == 0 {
+				break
+			}
+			page = resp.NextPage
+		}
+	}
+	if p.FetchFiles && i.PullRequestLinks != nil && i.Files == nil {
+		i.Files = []*github.CommitFile{}
+		for page := 1; ; {
+			var files []*github.CommitFile
+			var resp *github.Response
+			err := withRetry(ctx, func() (*github.Response, error) {
+				var err error
+				files, resp, err = client.PullRequests.ListFiles(
+					ctx, p.Owner, p.Repo, num,
+					&github.ListOptions{
+						Page:    page,
+						PerPage: perPage,
+					},
+				)
+				return resp, err
+			})
+			if err != nil {
+				return changed, err
+			}
+			i.Files = append(i.Files, files...)
+			changed = true
+			if resp.NextPage == 0 {
+				break
+			}
+			page = resp.NextPage
+		}
+	}
+	if p.FetchSizes && i.PullRequestLinks != nil && i.Additions == nil {
+		var pr *github.PullRequest
+		err := withRetry(ctx, func() (*github.Response, error) {
+			var resp *github.Response
+			var err error
+			pr, resp, err = client.PullRequests.Get(ctx, p.Owner, p.Repo, num)
+			return resp, err
+		})
+		if err != nil {
+			return changed, err
+		}
+		i.Additions = github.Int(pr.GetAdditions())
+		i.Deletions = github.Int(pr.GetDeletions())
+		changed = true
+	}
+	if !i.timelineComplete() {
+		// Resume after the pages saved by an interrupted refresh. The
+		// timeline is in chronological order, so those pages are unchanged.
+		page := 1
+		if i.TimelineNextPage > 0 {
+			page = i.TimelineNextPage
+		}
+		for {
+			var timeline []*github.Timeline
+			var resp *github.Response
+			err := withRetry(ctx, func() (*github.Response, error) {
+				var err error
+				timeline, resp, err = client.Issues.ListIssueTimeline(
+					ctx, p.Owner, p.Repo, num,
+					&github.ListOptions{
+						Page:    page,
+						PerPage: perPage,
+					},
+				)
+				return resp, err
+			})
+			if err != nil {
+				return changed, err
+			}
+			i.Timeline = append(i.Timeline, timeline...)
+			i.TimelineNextPage = resp.NextPage
+			changed = true
+			if resp.NextPage == 0 {
+				break
+			}
+			// Save each page so that an interrupted refresh does not have
+			// to fetch a long timeline from the start. Nothing else
+			// touches the issue or its file while its details are fetched.
+			p.saveIssue(i)
+			page = resp.NextPage
+		}
+	}
+	return changed, nil
+}
+
+// timelineComplete returns true if the issue's whole timeline has been
+// fetched.
+func (i *Issue) timelineComplete() bool {
+	return i.Timeline != nil && i.TimelineNextPage == 0
+}
+
+// LoadTeam reads the logins of the project's maintainers from path, one per
+// line. Blank lines and lines starting with '#' are ignored. A missing file
+// leaves the team empty so that nobody is considered a maintainer.
+func (p *Project) LoadTeam(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Printf("team file %s not found; treating nobody as a maintainer", path)
+			return nil
+		}
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.team[strings.ToLower(line)] = true
+	}
+	return nil
+}
+
+// isMaintainer returns true if u is a member of the project's team.
+func (p *Project) isMaintainer(u *github.User) bool {
+	return p.team[strings.ToLower(u.GetLogin())]
+}
+
+// Issues returns the project's issues ordered by number.
+func (p *Project) Issues() []*Issue {
+	nums := p.sortedIssues()
+	issues := make([]*Issue, len(nums))
+	for j, n := range nums {
+		issues[j] = p.issues[n]
+	}
+	return issues
+}
+
+func (p *Project) sortedIssues() []int {
+	n := make([]int, 0, len(p.issues))
+	for i := range p.issues {
+		n = append(n, i)
+	}
+	sort.Ints(n)
+	return n
+}
+
+// Filter returns a copy of the project containing only the issues for which
+// include returns true. The interned users, milestones and repos are shared
+// with the original.
+func (p *Project) Filter(include func(i *Issue) bool) *Project {
+	c := *p
+	c.issues = make(map[int]*Issue)
+	for n, i := range p.issues {
+		if include(i) {
+			c.issues[n] = i
+		}
+	}
+	return &c
+}
+
+// FilterByCreated returns the issues created in [since, until) in issue
+// number order. A zero since or until leaves that end of the range open.
+func (p *Project) FilterByCreated(since, until time.Time) []*Issue {
+	var issues []*Issue
+	for _, n := range p.sortedIssues() {
+		i := p.issues[n]
+		if i.CreatedAt == nil {
+			continue
+		}
+		if !since.IsZero() && i.CreatedAt.Before(since) {
+			continue
+		}
+		if !until.IsZero() && !i.CreatedAt.Before(until) {
+			continue
+		}
+		issues = append(issues, i)
+	}
+	return issues
+}
+
+// WithIssues returns a copy of the project containing only issues, which
+// must belong to p, sharing the interned users, milestones and repos with
+// the original.
+func (p *Project) WithIssues(issues []*Issue) *Project {
+	keep := make(map[*Issue]bool, len(issues))
+	for _, i := range issues {
+		keep[i] = true
+	}
+	return p.Filter(func(i *Issue) bool { return keep[i] })
+}
+
+func (p *Project) internUser(u **github.User) {
+	if id := (*u).GetID(); id != 0 {
+		if e := p.users[id]; e != nil {
+			*u = e
+		} else {
+			p.users[id] = *u
+		}
+	}
+}
+
+func (p *Project) internMilestone(m **github.Milestone) {
+	if id := (*m).GetID(); id != 0 {
+		if e := p.milestones[id]; e != nil {
+			*m = e
+		} else {
+			p.milestones[id] = *m
+		}
+	}
+}
+
+func (p *Project) internRepo(r **github.Repository) {
+	if id := (*r).GetID(); id != 0 {
+		if e := p.repos[id]; e != nil {
+			*r = e
+		} else {
+			p.repos[id] = *r
+		}
+	}
+}
+
+func (p *Project) internIssue(i *Issue) {
+	p.internUser(&i.User)
+	p.internUser(&i.Assignee)
+	p.internUser(&i.ClosedBy)
+	for j := range i.Assignees {
+		p.internUser(&i.Assignees[j])
+	}
+	p.internMilestone(&i.Milestone)
+	p.internRepo(&i.Repository)
+
+	for _, t := range i.Timeline {
+		p.internUser(&t.Actor)
+		p.internUser(&t.Assignee)
+		p.internMilestone(&t.Milestone)
+	}
+
+	for _, c := range i.Commits {
+		p.internUser(&c.Author)
+		p.internUser(&c.Committer)
+	}
+
+	for _, r := range i.Reviews {
+		p.internUser(&r.User)
+	}
+}
+
+// checkSchema returns an error if the project's cache, with the given schema
+// version, cannot be read.
+func (p *Project) checkSchema(version int) error {
+	switch {
+	case version == 0:
+		return fmt.Errorf("cache %s predates schema versions; remove it and refresh with -u", p.cacheDir)
+	case version > schemaVersion:
+		return fmt.Errorf("cache %s has schema version %d, newer than the supported %d; upgrade roachpulse",
+			p.cacheDir, version, schemaVersion)
+	case version < minSchemaVersion:
+		return fmt.Errorf("cache %s has schema version %d, older than the supported %d; remove it and refresh with -u",
+			p.cacheDir, version, minSchemaVersion)
+	}
+	return nil
+}
+
+// Load reads the project from its cache, which may be empty. It returns an
+// error if the cache was written in a format it cannot read.
+func (p *Project) Load() error {
+	var ok bool
+	Bench.Time("load meta", func() {
+		ok = loadJSON(filepath.Join(p.cacheDir, "meta"), p)
+	})
+	if ok {
+		if err := p.checkSchema(p.SchemaVersion); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	var loaded []*Issue
+	ndjsonPath := filepath.Join(p.cacheDir, ndjsonFile)
+	if _, err := os.Stat(ndjsonPath); p.Store == StoreNDJSON && err == nil {
+		fmt.Fprintf(Progress, "loading %s\n", ndjsonPath)
+		Bench.Time("load issues", func() {
+			loaded = loadNDJSON(ndjsonPath)
+		})
+	} else {
+		// The ndjson store falls back to any per-issue files so that an
+		// existing cache is converted by the next Save.
+		files, err := ioutil.ReadDir(p.cacheDir)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return nil
+		}
+		var names []string
+		for _, f := range files {
+			if n, _ := strconv.Atoi(f.Name()); n != 0 {
+				names = append(names, f.Name())
+			}
+		}
+		fmt.Fprintf(Progress, "loading %s (%d)\n", p.cacheDir, len(names))
+		Bench.Time("load issues", func() {
+			// Decode the files on a pool of workers. Each worker writes only
+			// to its own slots of loaded, and interning happens afterwards on
+			// this goroutine as the intern maps are not safe for concurrent
+			// use.
+			loaded = make([]*Issue, len(names))
+			jobs := make(chan int)
+			var wg sync.WaitGroup
+			for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := range jobs {
+						path := filepath.Join(p.cacheDir, names[j])
+						i := &Issue{}
+						if !loadJSON(path, i) {
+							continue
+						}
+						if i.Number == nil {
+							log.Printf("skipping %s: no issue number", path)
+							continue
+						}
+						loaded[j] = i
+					}
+				}()
+			}
+			for j := range names {
+				jobs <- j
+			}
+			close(jobs)
+			wg.Wait()
+		})
+	}
+	Bench.Time("intern", func() {
+		for _, i := range loaded {
+			if i == nil || i.Number == nil {
+				continue
+			}
+			p.internIssue(i)
+			p.issues[*i.Number] = i
+		}
+	})
+	fmt.Fprintf(Progress, "  done (%d) %.1fs\n", len(p.issues), time.Since(start).Seconds())
+	return nil
+}
+
+// Save writes the project's metadata and contributors to its cache, along
+// with its issues when using the ndjson store.
+func (p *Project) Save() {
+	p.SchemaVersion = schemaVersion
+	saveJSON(filepath.Join(p.cacheDir, "meta"), p)
+	p.saveContributors()
+	if p.Store == StoreNDJSON {
+		p.saveNDJSON(filepath.Join(p.cacheDir, ndjsonFile))
+	}
+}
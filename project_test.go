@@ -0,0 +1,654 @@
+package roachpulse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func init() {
+	Progress = ioutil.Discard
+}
+
+// fakeGitHub serves canned GitHub API responses for the repository o/r. The
+// issue list holds numbers, listPage of them per page, and every issue has
+// detailPages pages of timeline events, and of commits and reviews if it is
+// a pull request. Even numbered issues are pull requests. Every issue has
+// the given title, numbered, and was last updated at updated.
+type fakeGitHub struct {
+	numbers     []int
+	listPage    int
+	detailPages int
+	title       string
+	updated     string
+
+	mu       sync.Mutex
+	hits     map[string]int
+	listURLs []*url.URL
+}
+
+// fakeUser is the author of every issue, event, commit and review served by
+// fakeGitHub.
+var fakeUser = map[string]interface{}{"id": 7, "login": "alice"}
+
+func newFakeGitHub(numbers ...int) *fakeGitHub {
+	return &fakeGitHub{
+		numbers:     numbers,
+		listPage:    2,
+		detailPages: 2,
+		title:       "issue",
+		updated:     "2020-01-01T00:00:00Z",
+		hits:        make(map[string]int),
+	}
+}
+
+// hitCount returns the number of times the given path and page were
+// requested. Page 1 is also counted for requests without a page.
+func (f *fakeGitHub) hitCount(path string, page int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.hits[path+"?page="+strconv.Itoa(page)]
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page == 0 {
+		page = 1
+	}
+	f.mu.Lock()
+	f.hits[r.URL.Path+"?page="+strconv.Itoa(page)]++
+	f.mu.Unlock()
+
+	// paginate sets the Link header for a resource with last pages.
+	paginate := func(last int) {
+		if page < last {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?page=%d>; rel="next", <%s?page=%d>; rel="last"`,
+				r.URL.Path, page+1, r.URL.Path, last))
+		}
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/repos/o/r/issues":
+		f.mu.Lock()
+		f.listURLs = append(f.listURLs, r.URL)
+		f.mu.Unlock()
+		paginate((len(f.numbers) + f.listPage - 1) / f.listPage)
+		out := []map[string]interface{}{}
+		for j := (page - 1) * f.listPage; j < page*f.listPage && j < len(f.numbers); j++ {
+			out = append(out, f.issue(f.numbers[j]))
+		}
+		json.NewEncoder(w).Encode(out)
+	case len(parts) == 5 && parts[3] == "issues":
+		n, _ := strconv.Atoi(parts[4])
+		json.NewEncoder(w).Encode(f.issue(n))
+	case len(parts) == 6 && parts[5] == "timeline":
+		paginate(f.detailPages)
+		json.NewEncoder(w).Encode([]map[string]interface{}{{
+			"id":         page,
+			"event":      "commented",
+			"actor":      fakeUser,
+			"created_at": fmt.Sprintf("2020-01-%02dT00:00:00Z", page+1),
+		}})
+	case len(parts) == 6 && (parts[5] == "commits" || parts[5] == "reviews"):
+		paginate(f.detailPages)
+		json.NewEncoder(w).Encode([]map[string]interface{}{{
+			"sha":    fmt.Sprintf("sha%d", page),
+			"id":     page,
+			"state":  "APPROVED",
+			"user":   fakeUser,
+			"author": fakeUser,
+		}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// issue returns the JSON for issue n.
+func (f *fakeGitHub) issue(n int) map[string]interface{} {
+	issue := map[string]interface{}{
+		"id":         1000 + n,
+		"number":     n,
+		"title":      fmt.Sprintf("%s %d", f.title, n),
+		"user":       fakeUser,
+		"milestone":  map[string]interface{}{"id": 1, "title": "v1"},
+		"repository": map[string]interface{}{"id": 1, "name": "r"},
+		"created_at": "2020-01-01T00:00:00Z",
+		"updated_at": f.updated,
+	}
+	if n%2 == 0 {
+		issue["pull_request"] = map[string]interface{}{"url": "x"}
+	}
+	return issue
+}
+
+// makeClientForTest returns a client which sends its requests to baseURL,
+// typically that of an httptest.Server.
+func makeClientForTest(t testing.TB, baseURL string) *github.Client {
+	client, err := NewClient(http.DefaultClient, baseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// start serves f until the test completes and returns a client for it.
+func (f *fakeGitHub) start(t testing.TB) *github.Client {
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return makeClientForTest(t, srv.URL)
+}
+
+func TestRefresh(t *testing.T) {
+	f := newFakeGitHub(1, 2, 3, 4, 5)
+	client := f.start(t)
+	dir := t.TempDir()
+	p := NewProject("o", "r", dir)
+	if err := p.Refresh(context.Background(), client); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(p.issues) != 5 {
+		t.Fatalf("expected 5 issues, got %d", len(p.issues))
+	}
+	for n := 1; n <= 5; n++ {
+		i := p.issues[n]
+		if i == nil {
+			t.Fatalf("issue %d missing", n)
+		}
+		if want := fmt.Sprintf("issue %d", n); i.GetTitle() != want {
+			t.Errorf("issue %d: expected title %q, got %q", n, want, i.GetTitle())
+		}
+		if len(i.Timeline) != 2 {
+			t.Errorf("issue %d: expected 2 timeline events, got %d", n, len(i.Timeline))
+		}
+		if n%2 == 0 && (len(i.Commits) != 2 || len(i.Reviews) != 2) {
+			t.Errorf("pull request %d: expected 2 commits and reviews, got %d and %d",
+				n, len(i.Commits), len(i.Reviews))
+		}
+	}
+	if p.RefreshedAt.IsZero() {
+		t.Errorf("RefreshedAt not set")
+	}
+
+	// Every issue is saved to its own file, and the whole project reloads
+	// from the cache.
+	for n := 1; n <= 5; n++ {
+		if _, err := os.Stat(filepath.Join(dir, strconv.Itoa(n))); err != nil {
+			t.Errorf("issue %d not cached: %s", n, err)
+		}
+	}
+	q := NewProject("o", "r", dir)
+	if err := q.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.issues) != 5 {
+		t.Fatalf("expected 5 cached issues, got %d", len(q.issues))
+	}
+	if !q.RefreshedAt.Equal(p.RefreshedAt) {
+		t.Errorf("expected cached RefreshedAt %s, got %s", p.RefreshedAt, q.RefreshedAt)
+	}
+	for n, i := range q.issues {
+		if len(i.Timeline) != 2 || i.GetTitle() != p.issues[n].GetTitle() {
+			t.Errorf("issue %d: cached copy differs", n)
+		}
+	}
+}
+
+func TestRefreshSince(t *testing.T) {
+	dir := t.TempDir()
+	f := newFakeGitHub(1, 2, 3)
+	p := NewProject("o", "r", dir)
+	if err := p.Refresh(context.Background(), f.start(t)); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range f.listURLs {
+		if since := u.Query().Get("since"); since != "" {
+			t.Errorf("cold refresh sent since=%s", since)
+		}
+	}
+
+	// Only issue 3 has changed since the first refresh.
+	g := newFakeGitHub(3)
+	g.title = "updated"
+	g.updated = "2020-02-01T00:00:00Z"
+	q := NewProject("o", "r", dir)
+	if err := q.Load(); err != nil {
+		t.Fatal(err)
+	}
+	refreshedAt := q.RefreshedAt
+	if err := q.Refresh(context.Background(), g.start(t)); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.listURLs) != 1 {
+		t.Fatalf("expected 1 list request, got %d", len(g.listURLs))
+	}
+	want := refreshedAt.Format(time.RFC3339)
+	if since := g.listURLs[0].Query().Get("since"); since != want {
+		t.Errorf("expected since=%s, got since=%s", want, since)
+	}
+	if len(q.issues) != 3 {
+		t.Fatalf("expected 3 issues, got %d", len(q.issues))
+	}
+	if title := q.issues[3].GetTitle(); title != "updated 3" {
+		t.Errorf("expected issue 3 to be updated, got title %q", title)
+	}
+	if title := q.issues[1].GetTitle(); title != "issue 1" {
+		t.Errorf("expected issue 1 to be unchanged, got title %q", title)
+	}
+	if !q.RefreshedAt.After(refreshedAt) {
+		t.Errorf("RefreshedAt not advanced past %s", refreshedAt)
+	}
+}
+
+// TestRefreshIntern checks that the users, milestones and repositories
+// repeated across issues and their details share a single copy after a
+// refresh, and again after the cache is reloaded.
+func TestRefreshIntern(t *testing.T) {
+	dir := t.TempDir()
+	f := newFakeGitHub(1, 2, 3, 4)
+	p := NewProject("o", "r", dir)
+	if err := p.Refresh(context.Background(), f.start(t)); err != nil {
+		t.Fatal(err)
+	}
+	q := NewProject("o", "r", dir)
+	if err := q.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, p := range map[string]*Project{"refreshed": p, "loaded": q} {
+		if len(p.users) != 1 || len(p.milestones) != 1 || len(p.repos) != 1 {
+			t.Fatalf("%s: expected 1 user, milestone and repo, got %d, %d and %d",
+				name, len(p.users), len(p.milestones), len(p.repos))
+		}
+		user, milestone, repo := p.users[7], p.milestones[1], p.repos[1]
+		if user.GetLogin() != "alice" || milestone.GetTitle() != "v1" || repo.GetName() != "r" {
+			t.Fatalf("%s: unexpected interned user %v, milestone %v or repo %v",
+				name, user, milestone, repo)
+		}
+		for n, i := range p.issues {
+			if i.User != user || i.Milestone != milestone || i.Repository != repo {
+				t.Errorf("%s: issue %d not interned", name, n)
+			}
+			for _, e := range i.Timeline {
+				if e.Actor != user {
+					t.Errorf("%s: issue %d: timeline actor not interned", name, n)
+				}
+			}
+			for _, c := range i.Commits {
+				if c.Author != user {
+					t.Errorf("%s: pull request %d: commit author not interned", name, n)
+				}
+			}
+			for _, r := range i.Reviews {
+				if r.User != user {
+					t.Errorf("%s: pull request %d: reviewer not interned", name, n)
+				}
+			}
+		}
+	}
+}
+
+// checkPagesFetchedOnce fails the test unless every page of path up to last
+// was requested exactly once, and nothing beyond it.
+func checkPagesFetchedOnce(t *testing.T, f *fakeGitHub, path string, last int) {
+	t.Helper()
+	for page := 1; page <= last+1; page++ {
+		want := 1
+		if page > last {
+			want = 0
+		}
+		if got := f.hitCount(path, page); got != want {
+			t.Errorf("%s page %d: expected %d requests, got %d", path, page, want, got)
+		}
+	}
+}
+
+// TestRefreshPagination checks that the issue list, timeline, commit and
+// review loops follow NextPage through 2, 3 and then 0, fetching every page
+// exactly once, both on a cold refresh and on an incremental one.
+func TestRefreshPagination(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"cold", "incremental"} {
+		t.Run(name, func(t *testing.T) {
+			f := newFakeGitHub(1, 2, 3, 4, 5, 6)
+			f.detailPages = 3
+			if name == "incremental" {
+				// Every issue has changed since the cold refresh.
+				f.updated = "2020-02-01T00:00:00Z"
+			}
+			p := NewProject("o", "r", dir)
+			if err := p.Load(); err != nil {
+				t.Fatal(err)
+			}
+			if (name == "cold") != p.RefreshedAt.IsZero() {
+				t.Fatalf("unexpected RefreshedAt %s", p.RefreshedAt)
+			}
+			if err := p.Refresh(context.Background(), f.start(t)); err != nil {
+				t.Fatal(err)
+			}
+
+			checkPagesFetchedOnce(t, f, "/repos/o/r/issues", 3)
+			for n := 1; n <= 6; n++ {
+				checkPagesFetchedOnce(t, f, fmt.Sprintf("/repos/o/r/issues/%d/timeline", n), 3)
+				if len(p.issues[n].Timeline) != 3 {
+					t.Errorf("issue %d: expected 3 timeline events, got %d", n, len(p.issues[n].Timeline))
+				}
+				if n%2 != 0 {
+					continue
+				}
+				checkPagesFetchedOnce(t, f, fmt.Sprintf("/repos/o/r/pulls/%d/commits", n), 3)
+				checkPagesFetchedOnce(t, f, fmt.Sprintf("/repos/o/r/pulls/%d/reviews", n), 3)
+				if len(p.issues[n].Commits) != 3 || len(p.issues[n].Reviews) != 3 {
+					t.Errorf("pull request %d: expected 3 commits and reviews, got %d and %d",
+						n, len(p.issues[n].Commits), len(p.issues[n].Reviews))
+				}
+			}
+		})
+	}
+}
+
+// TestRefreshFailure checks that a refresh which fails part way through
+// fetching timelines leaves RefreshedAt unchanged on disk, so that the next
+// refresh lists the unfinished issues again.
+func TestRefreshFailure(t *testing.T) {
+	dir := t.TempDir()
+	p := NewProject("o", "r", dir)
+	if err := p.Refresh(context.Background(), newFakeGitHub(1, 2, 3).start(t)); err != nil {
+		t.Fatal(err)
+	}
+	refreshedAt := p.RefreshedAt
+
+	// Issues 2 and 3 are updated, and fetching the second page of issue 3's
+	// timeline fails.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := newFakeGitHub(2, 3)
+	f.title = "updated"
+	f.updated = "2020-02-01T00:00:00Z"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/o/r/issues/3/timeline" && r.URL.Query().Get("page") == "2" {
+			cancel()
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		f.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	q := NewProject("o", "r", dir)
+	if err := q.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Refresh(ctx, makeClientForTest(t, srv.URL)); err == nil {
+		t.Fatal("expected the refresh to fail")
+	}
+
+	r := NewProject("o", "r", dir)
+	if err := r.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !r.RefreshedAt.Equal(refreshedAt) {
+		t.Fatalf("expected RefreshedAt to stay %s, got %s", refreshedAt, r.RefreshedAt)
+	}
+
+	// The next refresh lists the issues updated since the last complete
+	// refresh, and finishes issue 3.
+	g := newFakeGitHub(2, 3)
+	g.title = "updated"
+	g.updated = "2020-02-01T00:00:00Z"
+	if err := r.Refresh(context.Background(), g.start(t)); err != nil {
+		t.Fatal(err)
+	}
+	if since := g.listURLs[0].Query().Get("since"); since != refreshedAt.Format(time.RFC3339) {
+		t.Errorf("expected since=%s, got since=%s", refreshedAt.Format(time.RFC3339), since)
+	}
+	for n := 2; n <= 3; n++ {
+		i := r.issues[n]
+		if want := fmt.Sprintf("updated %d", n); i.GetTitle() != want || len(i.Timeline) != 2 {
+			t.Errorf("issue %d: expected title %q and 2 timeline events, got %q and %d",
+				n, want, i.GetTitle(), len(i.Timeline))
+		}
+	}
+	if !r.RefreshedAt.After(refreshedAt) {
+		t.Errorf("RefreshedAt not advanced past %s", refreshedAt)
+	}
+}
+
+// TestRefreshResume checks that a refresh interrupted part way through an
+// issue's timeline resumes it from the first page it had not fetched,
+// rather than listing the issue as updated and starting again.
+func TestRefreshResume(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := newFakeGitHub(1)
+	f.detailPages = 4
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/o/r/issues/1/timeline" && r.URL.Query().Get("page") == "3" {
+			cancel()
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		f.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	p := NewProject("o", "r", dir)
+	if err := p.Refresh(ctx, makeClientForTest(t, srv.URL)); err == nil {
+		t.Fatal("expected the refresh to be interrupted")
+	}
+
+	q := NewProject("o", "r", dir)
+	if err := q.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if i := q.issues[1]; len(i.Timeline) != 2 || i.TimelineNextPage != 3 {
+		t.Fatalf("expected 2 cached events and next page 3, got %d and %d",
+			len(i.Timeline), i.TimelineNextPage)
+	}
+
+	g := newFakeGitHub(1)
+	g.detailPages = 4
+	if err := q.Refresh(context.Background(), g.start(t)); err != nil {
+		t.Fatal(err)
+	}
+	for page := 1; page <= 4; page++ {
+		want := 0
+		if page >= 3 {
+			want = 1
+		}
+		if got := g.hitCount("/repos/o/r/issues/1/timeline", page); got != want {
+			t.Errorf("timeline page %d: expected %d requests, got %d", page, want, got)
+		}
+	}
+	i := q.issues[1]
+	if len(i.Timeline) != 4 || !i.timelineComplete() {
+		t.Fatalf("expected a complete timeline of 4 events, got %d", len(i.Timeline))
+	}
+	for j, e := range i.Timeline {
+		if e.GetID() != j+1 {
+			t.Errorf("event %d: expected id %d, got %d", j, j+1, e.GetID())
+		}
+	}
+}
+
+// BenchmarkLoad loads a directory store of synthetic issues. Files are
+// decoded on GOMAXPROCS workers, so compare with -cpu 1 to see the speedup.
+func BenchmarkLoad(b *testing.B) {
+	for _, n := range []int{1000, 10000} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			dir := b.TempDir()
+			p := NewProject("o", "r", dir)
+			user := &github.User{ID: github.Int(7), Login: github.String("alice")}
+			created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			for num := 1; num <= n; num++ {
+				i := &Issue{Issue: github.Issue{
+					Number:    github.Int(num),
+					Title:     github.String(fmt.Sprintf("issue %d", num)),
+					User:      user,
+					CreatedAt: &created,
+				}}
+				for e := 0; e < 10; e++ {
+					i.Timeline = append(i.Timeline, &github.Timeline{
+						Event:     github.String("commented"),
+						Actor:     user,
+						CreatedAt: &created,
+					})
+				}
+				p.saveIssue(i)
+			}
+			p.Save()
+
+			b.ResetTimer()
+			for j := 0; j < b.N; j++ {
+				q := NewProject("o", "r", dir)
+				if err := q.Load(); err != nil {
+					b.Fatal(err)
+				}
+				if len(q.issues) != n {
+					b.Fatalf("expected %d issues, got %d", n, len(q.issues))
+				}
+			}
+		})
+	}
+}
+
+// TestLoadCorrupt checks that a truncated issue file is skipped rather than
+// aborting the load.
+func TestLoadCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	p := NewProject("o", "r", dir)
+	for n := 1; n <= 3; n++ {
+		p.saveIssue(&Issue{Issue: github.Issue{Number: github.Int(n)}})
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "4"), []byte(`{"number":4,"tit`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	q := NewProject("o", "r", dir)
+	if err := q.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.issues) != 3 {
+		t.Fatalf("expected 3 issues, got %d", len(q.issues))
+	}
+	for n := 1; n <= 3; n++ {
+		if q.issues[n] == nil {
+			t.Errorf("issue %d missing", n)
+		}
+	}
+}
+
+// TestLoadSchema checks that Load refuses caches whose schema version it
+// cannot read, including those written before the version was recorded.
+func TestLoadSchema(t *testing.T) {
+	testCases := []struct {
+		name string
+		meta string
+		want string
+	}{
+		{"missing", "", ""},
+		{"unversioned", `{"Owner":"o","Repo":"r"}`, "predates schema versions"},
+		{"zero", `{"Owner":"o","Repo":"r","SchemaVersion":0}`, "predates schema versions"},
+		{"oldest", fmt.Sprintf(`{"Owner":"o","Repo":"r","SchemaVersion":%d}`, minSchemaVersion), ""},
+		{"current", fmt.Sprintf(`{"Owner":"o","Repo":"r","SchemaVersion":%d}`, schemaVersion), ""},
+		{"newer", fmt.Sprintf(`{"Owner":"o","Repo":"r","SchemaVersion":%d}`, schemaVersion+1), "upgrade roachpulse"},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if c.meta != "" {
+				if err := ioutil.WriteFile(filepath.Join(dir, "meta"), []byte(c.meta), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, "1"), []byte(`{"number":1}`), 0644); err != nil {
+				t.Fatal(err)
+			}
+			p := NewProject("o", "r", dir)
+			err := p.Load()
+			switch {
+			case c.want == "" && err != nil:
+				t.Errorf("unexpected error: %s", err)
+			case c.want == "" && len(p.issues) != 1:
+				t.Errorf("expected 1 issue, got %d", len(p.issues))
+			case c.want != "" && err == nil:
+				t.Errorf("expected error containing %q", c.want)
+			case c.want != "" && !strings.Contains(err.Error(), c.want):
+				t.Errorf("expected error containing %q, got %q", c.want, err)
+			}
+		})
+	}
+}
+
+// TestLoadMissingNumber checks that a cache file holding an issue without a
+// number is skipped rather than loaded, and that such an issue is never
+// written to the cache.
+func TestLoadMissingNumber(t *testing.T) {
+	dir := t.TempDir()
+	p := NewProject("o", "r", dir)
+	for n := 1; n <= 2; n++ {
+		p.saveIssue(&Issue{Issue: github.Issue{Number: github.Int(n)}})
+	}
+	p.saveIssue(&Issue{Issue: github.Issue{Title: github.String("x")}})
+	if _, err := os.Stat(filepath.Join(dir, "0")); !os.IsNotExist(err) {
+		t.Errorf("issue without a number was cached: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "5"), []byte(`{"title":"x"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	q := NewProject("o", "r", dir)
+	if err := q.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.issues) != 2 {
+		t.Fatalf("expected 2 issues, got %d", len(q.issues))
+	}
+	for n := 1; n <= 2; n++ {
+		if q.issues[n] == nil {
+			t.Errorf("issue %d missing", n)
+		}
+	}
+}
+
+// TestRefreshIssueDryRun checks that a dry run of a single issue neither
+// fetches its details nor touches the cache.
+func TestRefreshIssueDryRun(t *testing.T) {
+	dir := t.TempDir()
+	f := newFakeGitHub(1, 2)
+	p := NewProject("o", "r", dir)
+	p.DryRun = true
+	if err := p.RefreshIssue(context.Background(), f.start(t), 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.hitCount("/repos/o/r/issues/2", 1); got != 1 {
+		t.Errorf("expected issue 2 to be fetched once, got %d", got)
+	}
+	for _, path := range []string{"issues/2/timeline", "pulls/2/commits", "pulls/2/reviews"} {
+		if got := f.hitCount("/repos/o/r/"+path, 1); got != 0 {
+			t.Errorf("dry run fetched %s", path)
+		}
+	}
+	if p.issues[2] != nil {
+		t.Errorf("dry run added issue 2 to the project")
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range files {
+		t.Errorf("dry run wrote %s", fi.Name())
+	}
+}
@@ -0,0 +1,110 @@
+package roachpulse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseQuery parses query terms of the form "key:value" into a predicate
+// that selects issues matching every term. Supported terms are:
+//
+//	is:open, is:closed, is:pr, is:issue, is:merged
+//	author:LOGIN
+//	label:NAME
+//	milestone:TITLE
+//	created:[<|<=|>|>=]YYYY-MM-DD
+//	closed:[<|<=|>|>=]YYYY-MM-DD
+//
+// A date without a comparison operator matches that day.
+func ParseQuery(args []string) (func(*Issue) bool, error) {
+	var preds []func(*Issue) bool
+	for _, arg := range args {
+		f := strings.SplitN(arg, ":", 2)
+		if len(f) != 2 || f[1] == "" {
+			return nil, fmt.Errorf("invalid query term %q: expected key:value", arg)
+		}
+		key, val := f[0], f[1]
+		if s, err := strconv.Unquote(val); err == nil {
+			val = s
+		}
+		var pred func(*Issue) bool
+		switch key {
+		case "is":
+			switch val {
+			case "open":
+				pred = func(i *Issue) bool { return i.GetState() == "open" }
+			case "closed":
+				pred = func(i *Issue) bool { return i.GetState() == "closed" }
+			case "pr":
+				pred = IsPR
+			case "issue":
+				pred = IsIssue
+			case "merged":
+				pred = func(i *Issue) bool { return i.merged() }
+			default:
+				return nil, fmt.Errorf("invalid query term %q: unknown is:%s", arg, val)
+			}
+		case "author":
+			pred = func(i *Issue) bool {
+				return strings.EqualFold(i.User.GetLogin(), val)
+			}
+		case "label":
+			pred = func(i *Issue) bool { return i.HasLabel(val) }
+		case "milestone":
+			pred = func(i *Issue) bool {
+				return i.Milestone != nil && i.Milestone.GetTitle() == val
+			}
+		case "created", "closed":
+			cmp, err := parseDateQuery(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid query term %q: %s", arg, err)
+			}
+			if key == "created" {
+				pred = func(i *Issue) bool { return i.CreatedAt != nil && cmp(*i.CreatedAt) }
+			} else {
+				pred = func(i *Issue) bool { return i.ClosedAt != nil && cmp(*i.ClosedAt) }
+			}
+		default:
+			return nil, fmt.Errorf("invalid query term %q: unknown key %q", arg, key)
+		}
+		preds = append(preds, pred)
+	}
+	return func(i *Issue) bool {
+		for _, pred := range preds {
+			if !pred(i) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// parseDateQuery parses a date optionally prefixed by a comparison operator
+// and returns a function reporting whether a time satisfies it.
+func parseDateQuery(s string) (func(time.Time) bool, error) {
+	op := ""
+	for _, o := range []string{">=", "<=", ">", "<"} {
+		if strings.HasPrefix(s, o) {
+			op, s = o, s[len(o):]
+			break
+		}
+	}
+	day, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q: expected YYYY-MM-DD", s)
+	}
+	next := day.AddDate(0, 0, 1)
+	switch op {
+	case ">":
+		return func(t time.Time) bool { return !t.Before(next) }, nil
+	case ">=":
+		return func(t time.Time) bool { return !t.Before(day) }, nil
+	case "<":
+		return func(t time.Time) bool { return t.Before(day) }, nil
+	case "<=":
+		return func(t time.Time) bool { return t.Before(next) }, nil
+	}
+	return func(t time.Time) bool { return !t.Before(day) && t.Before(next) }, nil
+}
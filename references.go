@@ -0,0 +1,234 @@
+package roachpulse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// repoOf returns the owner/repo of an issue of p.
+func (p *Project) repoOf(i *Issue) string {
+	if i.Source != "" {
+		return i.Source
+	}
+	return p.Name()
+}
+
+// referencingIssue returns the number of the issue or pull request which
+// referenced i in the cross-referenced timeline event t, or 0 if the
+// reference came from another repository.
+func (p *Project) referencingIssue(i *Issue, t *github.Timeline) int {
+	if t.GetEvent() != "cross-referenced" || t.Source == nil {
+		return 0
+	}
+	prefix := "/repos/" + p.repoOf(i) + "/issues/"
+	u := t.Source.GetURL()
+	j := strings.Index(u, prefix)
+	if j < 0 {
+		return 0
+	}
+	n, err := strconv.Atoi(u[j+len(prefix):])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// duplicateMarked returns the time the issue was last marked as a
+// duplicate, and false if it is not currently marked as one.
+func (i *Issue) duplicateMarked() (time.Time, bool) {
+	var marked time.Time
+	var ok bool
+	for _, t := range i.Timeline {
+		switch t.GetEvent() {
+		case "marked_as_duplicate":
+			marked, ok = t.GetCreatedAt(), true
+		case "unmarked_as_duplicate":
+			marked, ok = time.Time{}, false
+		}
+	}
+	return marked, ok
+}
+
+// ReportDuplicates prints the share of closed issues which were marked as
+// duplicates, followed by up to top canonical issues with the most
+// duplicates pointing at them. The timeline events marking a duplicate do
+// not name the canonical issue, so it is taken to be the issue which the
+// duplicate cross-referenced closest to being marked, as happens when
+// someone comments "Duplicate of #123".
+func ReportDuplicates(p *Project, top int) {
+	type key struct {
+		repo string
+		num  int
+	}
+	marked := make(map[key]time.Time)
+	var closed int
+	for _, i := range p.issues {
+		if i.PullRequestLinks != nil || i.ClosedAt == nil {
+			continue
+		}
+		closed++
+		if t, ok := i.duplicateMarked(); ok {
+			marked[key{p.repoOf(i), i.GetNumber()}] = t
+		}
+	}
+
+	// Find, for each duplicate, the issue it cross-referenced closest to
+	// being marked.
+	type candidate struct {
+		canonical *Issue
+		delta     time.Duration
+	}
+	best := make(map[key]candidate)
+	for _, i := range p.issues {
+		for _, t := range i.Timeline {
+			n := p.referencingIssue(i, t)
+			if n == 0 || n == i.GetNumber() {
+				continue
+			}
+			k := key{p.repoOf(i), n}
+			at, ok := marked[k]
+			if !ok {
+				continue
+			}
+			d := t.GetCreatedAt().Sub(at)
+			if d < 0 {
+				d = -d
+			}
+			if c, ok := best[k]; !ok || d < c.delta {
+				best[k] = candidate{canonical: i, delta: d}
+			}
+		}
+	}
+	counts := make(map[*Issue]int)
+	for _, c := range best {
+		counts[c.canonical]++
+	}
+	canonical := make([]*Issue, 0, len(counts))
+	for i := range counts {
+		canonical = append(canonical, i)
+	}
+	sort.Slice(canonical, func(a, b int) bool {
+		if ca, cb := counts[canonical[a]], counts[canonical[b]]; ca != cb {
+			return ca > cb
+		}
+		return canonical[a].GetNumber() < canonical[b].GetNumber()
+	})
+
+	fmt.Printf("closed as duplicate: %d/%d (%0.1f%%)\n", len(marked), closed,
+		100*ratio(len(marked), closed))
+	if unknown := len(marked) - len(best); unknown > 0 {
+		fmt.Printf("  canonical issue not found: %d\n", unknown)
+	}
+	if len(canonical) > top {
+		canonical = canonical[:top]
+	}
+	for _, i := range canonical {
+		fmt.Printf("  %s: %d duplicates: %s\n", i.id(), counts[i], i.GetTitle())
+	}
+}
+
+// WriteReferenceGraph writes the cross references between issues as a
+// Graphviz DOT digraph, with an edge from each referencing issue to the
+// issue it referenced. If seed is non-zero only the issues within depth
+// references of issue seed, in either direction, are included, which keeps
+// the graph of a large repository readable.
+func WriteReferenceGraph(w io.Writer, p *Project, seed, depth int) error {
+	type node struct {
+		repo string
+		num  int
+	}
+	type edge struct {
+		from, to node
+	}
+	byNode := make(map[node]*Issue)
+	adjacent := make(map[node][]node)
+	var edges []edge
+	seen := make(map[edge]bool)
+	for _, i := range p.issues {
+		to := node{p.repoOf(i), i.GetNumber()}
+		byNode[to] = i
+		for _, t := range i.Timeline {
+			n := p.referencingIssue(i, t)
+			if n == 0 || n == to.num {
+				continue
+			}
+			e := edge{node{to.repo, n}, to}
+			if seen[e] {
+				continue
+			}
+			seen[e] = true
+			edges = append(edges, e)
+			adjacent[e.from] = append(adjacent[e.from], e.to)
+			adjacent[e.to] = append(adjacent[e.to], e.from)
+		}
+	}
+
+	var include map[node]bool
+	if seed != 0 {
+		// Walk outwards from the seed a level at a time.
+		include = make(map[node]bool)
+		var level []node
+		for n := range byNode {
+			if n.num == seed {
+				include[n] = true
+				level = append(level, n)
+			}
+		}
+		for d := 0; d < depth && len(level) > 0; d++ {
+			var next []node
+			for _, n := range level {
+				for _, m := range adjacent[n] {
+					if !include[m] {
+						include[m] = true
+						next = append(next, m)
+					}
+				}
+			}
+			level = next
+		}
+	}
+
+	sort.Slice(edges, func(a, b int) bool {
+		ea, eb := edges[a], edges[b]
+		if ea.to != eb.to {
+			return ea.to.repo < eb.to.repo || ea.to.repo == eb.to.repo && ea.to.num < eb.to.num
+		}
+		return ea.from.repo < eb.from.repo || ea.from.repo == eb.from.repo && ea.from.num < eb.from.num
+	})
+	id := func(n node) string {
+		if len(p.sources) == 0 {
+			return strconv.Itoa(n.num)
+		}
+		return fmt.Sprintf("%s#%d", n.repo, n.num)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph references {\n")
+	labeled := make(map[node]bool)
+	for _, e := range edges {
+		if include != nil && (!include[e.from] || !include[e.to]) {
+			continue
+		}
+		for _, n := range []node{e.from, e.to} {
+			if labeled[n] {
+				continue
+			}
+			labeled[n] = true
+			label := id(n)
+			if i := byNode[n]; i != nil {
+				label += ": " + i.GetTitle()
+			}
+			fmt.Fprintf(bw, "\t%q [label=%q];\n", id(n), label)
+		}
+		fmt.Fprintf(bw, "\t%q -> %q;\n", id(e.from), id(e.to))
+	}
+	fmt.Fprintf(bw, "}\n")
+	return bw.Flush()
+}
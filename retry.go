@@ -0,0 +1,127 @@
+package roachpulse
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	// maxRetries bounds the number of times a request failing for reasons
+	// other than rate limiting is retried.
+	maxRetries    = 5
+	maxRetryDelay = time.Minute
+)
+
+// retryDelay is the delay before the first retry of a request failing for
+// reasons other than rate limiting. Each further retry waits twice as long,
+// up to maxRetryDelay. It is a variable so that tests need not wait.
+var retryDelay = time.Second
+
+// backoff returns the delay before the given retry, counting from 1. The
+// delay grows exponentially and is jittered so that concurrent workers
+// failing together do not retry in lockstep.
+func backoff(retry int) time.Duration {
+	d := maxRetryDelay
+	if retry < 32 {
+		if e := retryDelay << uint(retry-1); e < d {
+			d = e
+		}
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// retryable returns false for errors which retrying cannot fix, namely
+// client errors other than those caused by rate limiting.
+func retryable(err error) bool {
+	if e, ok := err.(*github.ErrorResponse); ok && e.Response != nil {
+		code := e.Response.StatusCode
+		return code < 400 || code >= 500 || code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// sleep waits for d or until ctx is done, whichever happens first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withRetry calls fn until it succeeds. When GitHub reports that a rate
+// limit was exceeded, it sleeps until the limit resets (or for the duration
+// GitHub asks for) and tries again. Other transient errors, such as 5xx
+// responses, are retried with exponential backoff up to maxRetries times
+// before being returned, and client errors are returned immediately.
+// Nothing is retried once ctx is done.
+func withRetry(ctx context.Context, fn func() (*github.Response, error)) error {
+	for attempt := 0; ; {
+		_, err := fn()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var wait time.Duration
+		switch e := err.(type) {
+		case *github.RateLimitError:
+			wait = time.Until(e.Rate.Reset.Time)
+			if wait < time.Second {
+				wait = time.Second
+			}
+			log.Printf("rate limited; sleeping until %s", e.Rate.Reset.Format(timeFormat))
+		case *github.AbuseRateLimitError:
+			wait = time.Minute
+			if e.RetryAfter != nil {
+				wait = *e.RetryAfter
+			}
+			log.Printf("secondary rate limit; sleeping for %s", wait)
+		default:
+			attempt++
+			if attempt > maxRetries || !retryable(err) {
+				return err
+			}
+			wait = backoff(attempt)
+			log.Printf("%s; retrying in %s", err, wait.Round(time.Millisecond))
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// FetchRateLimits returns the client's current rate limits, printing the
+// remaining core and search quota. Failing to fetch them is not fatal, as
+// they are only informational; nil is returned instead.
+func FetchRateLimits(ctx context.Context, client *github.Client) *github.RateLimits {
+	limits, _, err := client.RateLimits(ctx)
+	if err != nil {
+		log.Printf("fetching rate limits: %s", err)
+		return nil
+	}
+	for _, l := range []struct {
+		name string
+		rate *github.Rate
+	}{
+		{"core", limits.Core},
+		{"search", limits.Search},
+	} {
+		if l.rate == nil {
+			continue
+		}
+		fmt.Fprintf(Progress, "rate limit %s: %d/%d remaining, resets %s\n",
+			l.name, l.rate.Remaining, l.rate.Limit, l.rate.Reset.Format(timeFormat))
+	}
+	return limits
+}
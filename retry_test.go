@@ -0,0 +1,86 @@
+package roachpulse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// setRetryDelay sets retryDelay for the duration of the test.
+func setRetryDelay(t *testing.T, d time.Duration) {
+	old := retryDelay
+	retryDelay = d
+	t.Cleanup(func() { retryDelay = old })
+}
+
+// statusError returns the error go-github reports for a response with the
+// given status code.
+func statusError(code int) error {
+	return &github.ErrorResponse{Response: &http.Response{
+		StatusCode: code,
+		Request:    httptest.NewRequest("GET", "/repos/o/r/issues", nil),
+	}}
+}
+
+func TestWithRetry(t *testing.T) {
+	setRetryDelay(t, time.Millisecond)
+	testCases := []struct {
+		name     string
+		failures int
+		code     int
+		calls    int
+		fail     bool
+	}{
+		{"success", 0, 0, 1, false},
+		{"transient", maxRetries, http.StatusBadGateway, maxRetries + 1, false},
+		{"exhausted", maxRetries + 1, http.StatusBadGateway, maxRetries + 1, true},
+		{"rate-limited", 2, http.StatusTooManyRequests, 3, false},
+		{"not-found", 1, http.StatusNotFound, 1, true},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var calls int
+			err := withRetry(context.Background(), func() (*github.Response, error) {
+				calls++
+				if calls <= c.failures {
+					return nil, statusError(c.code)
+				}
+				return nil, nil
+			})
+			if c.fail != (err != nil) {
+				t.Errorf("expected failure %t, got %v", c.fail, err)
+			}
+			if calls != c.calls {
+				t.Errorf("expected %d calls, got %d", c.calls, calls)
+			}
+		})
+	}
+}
+
+// TestWithRetryCancel checks that cancelling the context cuts a backoff
+// short rather than waiting it out.
+func TestWithRetryCancel(t *testing.T) {
+	setRetryDelay(t, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls int
+	start := time.Now()
+	err := withRetry(ctx, func() (*github.Response, error) {
+		calls++
+		time.AfterFunc(10*time.Millisecond, cancel)
+		return nil, statusError(http.StatusBadGateway)
+	})
+	if err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+	if elapsed := time.Since(start); elapsed > time.Minute {
+		t.Errorf("backoff not cut short: returned after %s", elapsed)
+	}
+}
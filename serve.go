@@ -0,0 +1,108 @@
+package roachpulse
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// writePrometheus writes m in the Prometheus text exposition format. Every
+// series is a gauge labeled with the project, and histogram summaries are
+// exported as their mean, median, count and configured quantiles. A merged
+// project has a series for each of its repositories as well as one for the
+// whole project, labeled with the comma-separated repositories, which must
+// be excluded when summing across repositories.
+func writePrometheus(w io.Writer, m *Metrics) error {
+	all := append([]*Metrics{m}, m.Repos...)
+	var err error
+	gauge := func(name, help string, field func(m *Metrics) float64) {
+		if err != nil {
+			return
+		}
+		_, err = fmt.Fprintf(w, "# HELP roachpulse_%s %s\n# TYPE roachpulse_%s gauge\n", name, help, name)
+		for _, m := range all {
+			if err != nil {
+				return
+			}
+			_, err = fmt.Fprintf(w, "roachpulse_%s{project=%q} %g\n", name, m.Project, field(m))
+		}
+	}
+	summary := func(name, help string, field func(m *Metrics) Summary) {
+		gauge(name+"_mean", help+" (mean)", func(m *Metrics) float64 {
+			return field(m).Mean
+		})
+		gauge(name+"_median", help+" (median)", func(m *Metrics) float64 {
+			return float64(field(m).Median)
+		})
+		gauge(name+"_count", help+" (count)", func(m *Metrics) float64 {
+			return float64(field(m).Count)
+		})
+		if err != nil {
+			return
+		}
+		_, err = fmt.Fprintf(w, "# HELP roachpulse_%s %s (quantiles)\n# TYPE roachpulse_%s gauge\n",
+			name, help, name)
+		for _, m := range all {
+			s := field(m)
+			names := make([]string, 0, len(s.Quantiles))
+			for q := range s.Quantiles {
+				names = append(names, q)
+			}
+			sort.Strings(names)
+			for _, q := range names {
+				if err != nil {
+					return
+				}
+				_, err = fmt.Fprintf(w, "roachpulse_%s{project=%q,quantile=%q} %d\n",
+					name, m.Project, strings.TrimPrefix(q, "p"), s.Quantiles[q])
+			}
+		}
+	}
+
+	gauge("issues", "Number of issues.", func(m *Metrics) float64 {
+		return float64(m.Issues)
+	})
+	gauge("open_issues", "Number of open issues.", func(m *Metrics) float64 {
+		return float64(m.OpenIssues)
+	})
+	gauge("pull_requests", "Number of pull requests.", func(m *Metrics) float64 {
+		return float64(m.PullRequests)
+	})
+	gauge("open_pull_requests", "Number of open pull requests.", func(m *Metrics) float64 {
+		return float64(m.OpenPullRequests)
+	})
+	summary("pr_age_days", "Days from creation to close of pull requests", func(m *Metrics) Summary {
+		return m.PRAgeDays
+	})
+	summary("issue_close_days", "Days from creation to close of issues", func(m *Metrics) Summary {
+		return m.IssueCloseDays
+	})
+	summary("first_response_hours", "Hours until the first maintainer response to issues", func(m *Metrics) Summary {
+		return m.FirstResponseHours
+	})
+	gauge("pr_merge_ratio", "Fraction of closed pull requests that were merged.", func(m *Metrics) float64 {
+		return m.MergeRatio
+	})
+	gauge("community_pr_merge_ratio", "Fraction of closed community pull requests that were merged.",
+		func(m *Metrics) float64 {
+			return m.CommunityMergeRatio
+		})
+	return err
+}
+
+// ServeMetrics serves the metrics for p on addr at /metrics. The metrics are
+// recomputed from the in-memory project on every scrape; the project is not
+// refreshed from GitHub.
+func ServeMetrics(addr string, p *Project) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := writePrometheus(w, ComputeMetrics(p)); err != nil {
+			fmt.Fprintf(Progress, "serving metrics: %s\n", err)
+		}
+	})
+	fmt.Fprintf(Progress, "serving metrics on %s/metrics\n", addr)
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,50 @@
+package roachpulse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestWritePrometheus checks that a merged project exports a series for each
+// of its repositories alongside the series for the whole project, and that
+// each metric is described only once.
+func TestWritePrometheus(t *testing.T) {
+	summary := func(median int64) Summary {
+		return Summary{Count: 1, Median: median, Quantiles: map[string]int64{"p90": median}}
+	}
+	m := &Metrics{
+		Project:   "a/b,c/d",
+		Issues:    3,
+		PRAgeDays: summary(2),
+		Repos: []*Metrics{
+			{Project: "a/b", Issues: 1, PRAgeDays: summary(1)},
+			{Project: "c/d", Issues: 2, PRAgeDays: summary(3)},
+		},
+	}
+	var buf bytes.Buffer
+	if err := writePrometheus(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"roachpulse_issues{project=\"a/b,c/d\"} 3\n",
+		"roachpulse_issues{project=\"a/b\"} 1\n",
+		"roachpulse_issues{project=\"c/d\"} 2\n",
+		"roachpulse_pr_age_days_median{project=\"a/b\"} 1\n",
+		"roachpulse_pr_age_days{project=\"c/d\",quantile=\"90\"} 3\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in\n%s", want, out)
+		}
+	}
+	for _, want := range []string{
+		"# TYPE roachpulse_issues gauge\n",
+		"# TYPE roachpulse_pr_age_days gauge\n",
+	} {
+		if n := strings.Count(out, want); n != 1 {
+			t.Errorf("expected %q once, found %d times", want, n)
+		}
+	}
+}
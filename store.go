@@ -0,0 +1,190 @@
+package roachpulse
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// The cache supports two storage backends, selected by -store. The "dir"
+// store keeps one JSON file per issue and saves each issue as soon as its
+// details are fetched. The "ndjson" store keeps every issue in a single
+// gzip-compressed file of newline-delimited JSON, which loads much faster
+// for large projects but is only written once a refresh completes. Both
+// keep the project metadata in the "meta" file.
+const (
+	StoreDir    = "dir"
+	StoreNDJSON = "ndjson"
+)
+
+// ndjsonFile is the name of the ndjson store within the cache directory.
+const ndjsonFile = "issues.ndjson.gz"
+
+// saveIssue persists a single issue. The ndjson store rewrites all issues
+// at once, so it defers to the next call to p.save.
+func (p *Project) saveIssue(i *Issue) {
+	if i.Number == nil {
+		log.Printf("%s: not caching issue without a number", p.cacheDir)
+		return
+	}
+	if p.Store == StoreDir {
+		saveJSON(filepath.Join(p.cacheDir, strconv.Itoa(i.GetNumber())), i)
+	}
+}
+
+// removeIssue drops an issue from the project and the dir store. The ndjson
+// store is rewritten by the next call to p.save.
+func (p *Project) removeIssue(num int) {
+	if p.Store == StoreDir {
+		err := os.Remove(filepath.Join(p.cacheDir, strconv.Itoa(num)))
+		if err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+	}
+	delete(p.issues, num)
+}
+
+// loadNDJSON reads the issues in the ndjson store.
+func loadNDJSON(path string) []*Issue {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	z, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var issues []*Issue
+	d := json.NewDecoder(z)
+	for n := 1; ; n++ {
+		i := &Issue{}
+		if err := d.Decode(i); err == io.EOF {
+			break
+		} else if err != nil {
+			log.Fatalf("%s: %s", path, err)
+		}
+		if i.Number == nil {
+			log.Printf("skipping %s record %d: no issue number", path, n)
+			continue
+		}
+		issues = append(issues, i)
+	}
+	return issues
+}
+
+// saveNDJSON writes every issue in the project to the ndjson store in issue
+// number order.
+func (p *Project) saveNDJSON(path string) {
+	err := WriteFile(path, func(w io.Writer) error {
+		z := gzip.NewWriter(w)
+		e := json.NewEncoder(z)
+		for _, n := range p.sortedIssues() {
+			if err := e.Encode(p.issues[n]); err != nil {
+				return err
+			}
+		}
+		return z.Close()
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// cacheSize returns the total size of the files in the cache directory.
+func (p *Project) cacheSize() (int64, error) {
+	files, err := ioutil.ReadDir(p.cacheDir)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, f := range files {
+		if f.Mode().IsRegular() {
+			n += f.Size()
+		}
+	}
+	return n, nil
+}
+
+// Compact rewrites every loaded issue in the project's store, dropping any
+// fields written by older versions which the Issue struct no longer has,
+// and removes temporary files left behind by interrupted writes. It returns
+// the size of the cache before and after. The metadata, and so RefreshedAt,
+// is left untouched.
+func (p *Project) Compact() (before, after int64, err error) {
+	if before, err = p.cacheSize(); err != nil {
+		return 0, 0, err
+	}
+	tmps, err := filepath.Glob(filepath.Join(p.cacheDir, "*.tmp"))
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, tmp := range tmps {
+		if err := os.Remove(tmp); err != nil {
+			return 0, 0, err
+		}
+	}
+	if p.Store == StoreNDJSON {
+		p.saveNDJSON(filepath.Join(p.cacheDir, ndjsonFile))
+	} else {
+		for _, i := range p.issues {
+			p.saveIssue(i)
+		}
+	}
+	if after, err = p.cacheSize(); err != nil {
+		return 0, 0, err
+	}
+	return before, after, nil
+}
+
+// ProjectCacheDir returns the directory within root holding the cache for
+// owner/repo, so that projects sharing a root do not collide.
+func ProjectCacheDir(root, owner, repo string) string {
+	return filepath.Join(root, owner, repo)
+}
+
+// MigrateLegacyCache moves a cache written directly into root, before caches
+// were namespaced by project, into dir if it belongs to owner/repo and dir
+// does not exist yet. A legacy cache for another project is left alone.
+func MigrateLegacyCache(root, dir, owner, repo string) error {
+	var legacy struct {
+		Owner, Repo string
+	}
+	meta := filepath.Join(root, "meta")
+	if _, err := os.Stat(meta); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	loadJSON(meta, &legacy)
+	if legacy.Owner != owner || legacy.Repo != repo {
+		return nil
+	}
+
+	files, err := ioutil.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	fmt.Fprintf(Progress, "moving %s/%s cache from %s to %s\n", owner, repo, root, dir)
+	for _, f := range files {
+		name := f.Name()
+		if n, _ := strconv.Atoi(name); n == 0 && name != "meta" && name != usersFile && name != ndjsonFile {
+			continue
+		}
+		if err := os.Rename(filepath.Join(root, name), filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package roachpulse
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+// TestProjectCacheDirs checks that projects sharing a cache root are given
+// distinct directories, which keep their issues apart even when the issue
+// numbers overlap.
+func TestProjectCacheDirs(t *testing.T) {
+	root := t.TempDir()
+	names := [][2]string{{"cockroachdb", "cockroach"}, {"cockroachdb", "pebble"}}
+	dirs := make(map[string]bool)
+	for _, name := range names {
+		dir := ProjectCacheDir(root, name[0], name[1])
+		if dirs[dir] {
+			t.Fatalf("%s/%s: cache directory %s shared with another project", name[0], name[1], dir)
+		}
+		dirs[dir] = true
+		if !strings.HasPrefix(dir, root+string(filepath.Separator)) {
+			t.Fatalf("%s/%s: cache directory %s outside of %s", name[0], name[1], dir, root)
+		}
+	}
+
+	for _, name := range names {
+		dir := ProjectCacheDir(root, name[0], name[1])
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		p := NewProject(name[0], name[1], dir)
+		p.issues[1] = &Issue{Issue: github.Issue{
+			Number: github.Int(1),
+			Title:  github.String(name[1] + " issue"),
+		}}
+		p.saveIssue(p.issues[1])
+		p.Save()
+	}
+
+	for _, name := range names {
+		p := NewProject(name[0], name[1], ProjectCacheDir(root, name[0], name[1]))
+		if err := p.Load(); err != nil {
+			t.Fatal(err)
+		}
+		if len(p.issues) != 1 {
+			t.Fatalf("%s/%s: expected 1 issue, got %d", name[0], name[1], len(p.issues))
+		}
+		if want := name[1] + " issue"; p.issues[1].GetTitle() != want {
+			t.Errorf("%s/%s: expected issue 1 titled %q, got %q", name[0], name[1], want, p.issues[1].GetTitle())
+		}
+		if p.Owner != name[0] || p.Repo != name[1] {
+			t.Errorf("%s/%s: loaded meta for %s/%s", name[0], name[1], p.Owner, p.Repo)
+		}
+	}
+}
+
+// writeFiles creates the named files in dir with the given contents.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// listFiles returns the sorted names of the regular files in dir, or nil if
+// it does not exist.
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, fi := range infos {
+		if fi.Mode().IsRegular() {
+			names = append(names, fi.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestMigrateLegacyCache(t *testing.T) {
+	legacy := map[string]string{
+		"meta":    `{"Owner":"o","Repo":"r","SchemaVersion":1}`,
+		"1":       `{"number":1,"title":"legacy 1"}`,
+		"2":       `{"number":2,"title":"legacy 2"}`,
+		usersFile: `[]`,
+		"notes":   "not part of the cache",
+	}
+	testCases := []struct {
+		name string
+		// root and dir are the files in the cache root and the project's
+		// directory before migrating.
+		root, dir map[string]string
+		// wantRoot and wantDir are the files in each afterwards.
+		wantRoot, wantDir []string
+	}{
+		{
+			name:     "legacy",
+			root:     legacy,
+			wantRoot: []string{"notes"},
+			wantDir:  []string{"1", "2", "meta", usersFile},
+		},
+		{
+			name:     "other-project",
+			root:     map[string]string{"meta": `{"Owner":"o","Repo":"other"}`, "1": `{"number":1}`},
+			wantRoot: []string{"1", "meta"},
+		},
+		{
+			name:     "already-migrated",
+			root:     map[string]string{"notes": "not part of the cache"},
+			dir:      map[string]string{"meta": `{"Owner":"o","Repo":"r"}`, "1": `{"number":1}`},
+			wantRoot: []string{"notes"},
+			wantDir:  []string{"1", "meta"},
+		},
+		{
+			name:     "destination-exists",
+			root:     legacy,
+			dir:      map[string]string{"meta": `{"Owner":"o","Repo":"r"}`, "3": `{"number":3}`},
+			wantRoot: []string{"1", "2", "meta", "notes", usersFile},
+			wantDir:  []string{"3", "meta"},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := ProjectCacheDir(root, "o", "r")
+			writeFiles(t, root, c.root)
+			if c.dir != nil {
+				writeFiles(t, dir, c.dir)
+			}
+
+			// Migrating twice checks that a migrated cache is left alone.
+			sort.Strings(c.wantRoot)
+			sort.Strings(c.wantDir)
+			for j := 0; j < 2; j++ {
+				if err := MigrateLegacyCache(root, dir, "o", "r"); err != nil {
+					t.Fatal(err)
+				}
+				if got := listFiles(t, root); strings.Join(got, ",") != strings.Join(c.wantRoot, ",") {
+					t.Errorf("migration %d: expected %s in the root, got %s", j+1, c.wantRoot, got)
+				}
+				if got := listFiles(t, dir); strings.Join(got, ",") != strings.Join(c.wantDir, ",") {
+					t.Errorf("migration %d: expected %s in the project directory, got %s", j+1, c.wantDir, got)
+				}
+			}
+		})
+	}
+
+	// The migrated issues load from the project's directory.
+	root := t.TempDir()
+	writeFiles(t, root, legacy)
+	dir := ProjectCacheDir(root, "o", "r")
+	if err := MigrateLegacyCache(root, dir, "o", "r"); err != nil {
+		t.Fatal(err)
+	}
+	p := NewProject("o", "r", dir)
+	if err := p.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.issues) != 2 || p.issues[1].GetTitle() != "legacy 1" || p.issues[2].GetTitle() != "legacy 2" {
+		t.Errorf("migrated issues not loaded: %v", p.issues)
+	}
+}
@@ -0,0 +1,76 @@
+package roachpulse
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// Issue returns the cached issue with the given number, or nil if there is
+// none. In a merged project the first repository's issue is returned.
+func (p *Project) Issue(num int) *Issue {
+	if len(p.sources) == 0 {
+		return p.issues[num]
+	}
+	for _, i := range p.Issues() {
+		if i.GetNumber() == num {
+			return i
+		}
+	}
+	return nil
+}
+
+// WriteTimeline writes a human-readable log of the issue's timeline, one
+// event per line in the order GitHub returned them, which is chronological.
+// Each line holds the event's time, name and actor followed by the details
+// of the events we know about, such as the label added or removed.
+func (i *Issue) WriteTimeline(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "%s %s\n", i.Ref(), i.GetTitle()); err != nil {
+		return err
+	}
+	for _, t := range i.Timeline {
+		when := "-"
+		if t.CreatedAt != nil {
+			when = t.CreatedAt.UTC().Format(timeFormat)
+		}
+		actor := t.Actor.GetLogin()
+		if actor == "" {
+			actor = "-"
+		}
+		line := fmt.Sprintf("  %-19s  %-22s  %-20s  %s", when, t.GetEvent(), actor, timelineDetail(t))
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timelineDetail returns the payload of interest for a timeline event, or
+// an empty string for events without one.
+func timelineDetail(t *github.Timeline) string {
+	switch t.GetEvent() {
+	case "labeled", "unlabeled":
+		return t.Label.GetName()
+	case "milestoned", "demilestoned":
+		return t.Milestone.GetTitle()
+	case "assigned", "unassigned":
+		return t.Assignee.GetLogin()
+	case "renamed":
+		if t.Rename != nil {
+			return fmt.Sprintf("%q -> %q", t.Rename.GetFrom(), t.Rename.GetTo())
+		}
+	case "cross-referenced":
+		if t.Source != nil {
+			return t.Source.GetURL()
+		}
+	case "referenced", "closed", "merged", "committed", "head_ref_force_pushed":
+		if id := t.GetCommitID(); len(id) > 7 {
+			return id[:7]
+		} else if id != "" {
+			return id
+		}
+	}
+	return ""
+}